@@ -78,6 +78,9 @@ func main() {
 	if config.EnableWS {
 		go athena.ListenWS()
 	}
+	if config.EnableMSHost {
+		go athena.ListenMS()
+	}
 	if !*cliFlag {
 		go athena.ListenInput()
 	}
@@ -180,3 +180,53 @@ func TestInvited(t *testing.T) {
 		t.Errorf("unexpected value for invited length, got %d, want %d", len(a.invited), 0)
 	}
 }
+
+func TestPassword(t *testing.T) {
+	a := NewArea(AreaData{Max_players: 1}, 50, 0, EviAny)
+
+	if a.CheckPassword("") {
+		t.Errorf("checking password of area without password, got %t, want %t", true, false)
+	}
+	if err := a.SetPassword("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if !a.CheckPassword("hunter2") {
+		t.Errorf("checking correct password, got %t, want %t", false, true)
+	}
+	if a.CheckPassword("hunter3") {
+		t.Errorf("checking incorrect password, got %t, want %t", true, false)
+	}
+
+	// Resetting the area removes the password.
+	a.Reset()
+	if a.HasPassword() {
+		t.Errorf("area has password after reset, got %t, want %t", true, false)
+	}
+
+	a.AddChar(0)
+	if !a.IsFull() {
+		t.Errorf("area at capacity is not full, got %t, want %t", false, true)
+	}
+}
+
+func TestDuplicateChars(t *testing.T) {
+	a := NewArea(AreaData{Allow_dupes: true}, 50, 0, EviAny)
+
+	// Two clients join with CharID 0.
+	if !a.AddChar(0) || !a.AddChar(0) {
+		t.Errorf("adding duplicate player to area: got %t, want %t", false, true)
+	}
+	if a.taken[0] != 2 {
+		t.Errorf("unexpected value for character uses, got %d, want %d", a.taken[0], 2)
+	}
+	if a.IsTaken(0) {
+		t.Errorf("character taken in duplicate area, got %t, want %t", true, false)
+	}
+
+	// One switches to CharID 1, and the other leaves.
+	a.SwitchChar(0, 1)
+	a.RemoveChar(0)
+	if a.taken[0] != 0 || a.taken[1] != 1 {
+		t.Errorf("unexpected character uses, got (%d, %d), want (%d, %d)", a.taken[0], a.taken[1], 0, 1)
+	}
+}
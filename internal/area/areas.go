@@ -17,12 +17,20 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>. */
 package area
 
 import (
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/MangosArentLiterature/Athena/internal/game"
 	"github.com/MangosArentLiterature/Athena/internal/sliceutil"
+	"github.com/MangosArentLiterature/Athena/internal/vote"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// AreaTimers is the number of timers each area has. Area timers use IDs 1 through AreaTimers.
+const AreaTimers = 4
+
 type EvidenceMode int
 type Status int
 type Lock int
@@ -61,11 +69,35 @@ type TestimonyRecorder struct {
 	State     TRState
 }
 
+// MusicChannels is the number of music channels supported by clients.
+// Channel 0 is the main music channel, and channel AmbienceChannel plays the area's ambience.
+const (
+	MusicChannels   = 4
+	AmbienceChannel = 1
+)
+
+// GameOver is an area's penalty sequence, played when a penalty bar reaches 0.
+type GameOver struct {
+	Animation string
+	Music     string
+	Message   string
+	Lock      bool
+}
+
+// Music is a song playing on one of an area's music channels.
+type Music struct {
+	Name    string
+	Channel int
+	Looping bool
+	Effects int
+}
+
 type Area struct {
+	id       int
 	data     AreaData
 	defaults defaults
 	mu       sync.Mutex
-	taken    []bool
+	taken    []int
 	players  int
 	defhp    int
 	prohp    int
@@ -77,20 +109,56 @@ type Area struct {
 	status   Status
 	lock     Lock
 	invited  []int
+	password []byte
 	doc      string
 	tr       TestimonyRecorder
+	music    map[int]Music
+	recentIC [][]string
+	timers   [AreaTimers]Timer
+	jukebox  Jukebox
+	musicL   []string
+	bgL      []string
+	reserved map[int]int
+	assigned map[int]string
+	judgeLck bool
+	caseS    CaseSession
+	jury     *vote.Vote
+	jurors   []int
+	poll     *vote.Vote
+	game     *game.Game
+	turns    TurnOrder
+	deck     Deck
 }
 
 type AreaData struct {
-	Name          string `toml:"name"`
-	Evi_mode      string `toml:"evidence_mode"`
-	Allow_iniswap bool   `toml:"allow_iniswap"`
-	Force_noint   bool   `toml:"force_nointerrupt"`
-	Bg            string `toml:"background"`
-	Allow_cms     bool   `toml:"allow_cms"`
-	Force_bglist  bool   `toml:"force_bglist"`
-	Lock_bg       bool   `toml:"lock_bg"`
-	Lock_music    bool   `toml:"lock_music"`
+	Name          string   `toml:"name"`
+	Hub           string   `toml:"hub"`
+	Evi_mode      string   `toml:"evidence_mode"`
+	Allow_iniswap bool     `toml:"allow_iniswap"`
+	Force_noint   bool     `toml:"force_nointerrupt"`
+	Bg            string   `toml:"background"`
+	Allow_cms     bool     `toml:"allow_cms"`
+	Force_bglist  bool     `toml:"force_bglist"`
+	Lock_bg       bool     `toml:"lock_bg"`
+	Lock_music    bool     `toml:"lock_music"`
+	Ambience      string   `toml:"ambience"`
+	Jukebox       bool     `toml:"jukebox"`
+	Music_file    string   `toml:"music_file"`
+	Music_cats    []string `toml:"music_categories"`
+	Bg_file       string   `toml:"background_file"`
+	Allow_chars   []string `toml:"allowed_characters"`
+	Deny_chars    []string `toml:"denied_characters"`
+	Mod_chars     []string `toml:"mod_characters"`
+	Allow_dupes   bool     `toml:"allow_duplicate_characters"`
+	Restrict_pos  bool     `toml:"restrict_positions"`
+	Game_over     bool     `toml:"game_over"`
+	GO_anim       string   `toml:"game_over_animation"`
+	GO_music      string   `toml:"game_over_music"`
+	GO_msg        string   `toml:"game_over_message"`
+	GO_lock       bool     `toml:"game_over_lock"`
+	Turn_timeout  string   `toml:"turn_timeout"`
+	Deck_file     string   `toml:"deck_file"`
+	Max_players   int      `toml:"max_players"`
 }
 
 type defaults struct {
@@ -102,6 +170,7 @@ type defaults struct {
 	force_bglist  bool
 	lock_bg       bool
 	lock_music    bool
+	turn_timeout  time.Duration
 }
 
 // NewArea returns a new area.
@@ -118,15 +187,33 @@ func NewArea(data AreaData, charlen int, bufsize int, evi_mode EvidenceMode) *Ar
 			lock_bg:       data.Lock_bg,
 			lock_music:    data.Lock_music,
 		},
-		taken:    make([]bool, charlen),
+		taken:    make([]int, charlen),
 		defhp:    10,
 		prohp:    10,
 		buffer:   make([]string, bufsize),
 		last_msg: -1,
+		music:    defaultMusic(data.Ambience),
+		jukebox:  Jukebox{enabled: data.Jukebox},
+		reserved: make(map[int]int),
+		assigned: make(map[int]string),
 		evi_mode: evi_mode,
 	}
 }
 
+// ID returns the area's ID.
+func (a *Area) ID() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.id
+}
+
+// SetID sets the area's ID.
+func (a *Area) SetID(id int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.id = id
+}
+
 // Name returns the area's name.
 func (a *Area) Name() string {
 	a.mu.Lock()
@@ -139,7 +226,7 @@ func (a *Area) Taken() []string {
 	a.mu.Lock()
 	var takenList []string
 	for _, t := range a.taken {
-		if t {
+		if t > 0 && !a.data.Allow_dupes {
 			takenList = append(takenList, "-1")
 		} else {
 			takenList = append(takenList, "0")
@@ -154,10 +241,10 @@ func (a *Area) AddChar(char int) bool {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	if char != -1 {
-		if a.taken[char] {
+		if a.taken[char] > 0 && !a.data.Allow_dupes {
 			return false
 		} else {
-			a.taken[char] = true
+			a.taken[char]++
 		}
 	}
 	a.players++
@@ -170,16 +257,16 @@ func (a *Area) SwitchChar(old int, new int) bool {
 	defer a.mu.Unlock()
 	if new == -1 {
 		if old != -1 {
-			a.taken[old] = false
+			a.release(old)
 		}
 		return true
 	} else {
-		if a.taken[new] {
+		if a.taken[new] > 0 && !a.data.Allow_dupes {
 			return false
 		} else {
-			a.taken[new] = true
+			a.taken[new]++
 			if old != -1 {
-				a.taken[old] = false
+				a.release(old)
 			}
 		}
 		return true
@@ -190,12 +277,26 @@ func (a *Area) SwitchChar(old int, new int) bool {
 func (a *Area) RemoveChar(char int) {
 	a.mu.Lock()
 	if char != -1 {
-		a.taken[char] = false
+		a.release(char)
 	}
 	a.players--
 	a.mu.Unlock()
 }
 
+// release frees one use of a character. The caller must hold the area's lock.
+func (a *Area) release(char int) {
+	if a.taken[char] > 0 {
+		a.taken[char]--
+	}
+}
+
+// DuplicateChars returns whether multiple players can use the same character in the area.
+func (a *Area) DuplicateChars() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.data.Allow_dupes
+}
+
 // HP returns the values of the area's def and pro HP bars.
 func (a *Area) HP() (int, int) {
 	a.mu.Lock()
@@ -234,7 +335,7 @@ func (a *Area) PlayerCount() int {
 func (a *Area) Evidence() []string {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	return a.evidence
+	return append([]string{}, a.evidence...)
 }
 
 // AddEvidence adds a piece of evidence to the area.
@@ -407,7 +508,7 @@ func (a *Area) IsTaken(char int) bool {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	if char != -1 {
-		return a.taken[char]
+		return a.taken[char] > 0 && !a.data.Allow_dupes
 	} else {
 		return false
 	}
@@ -493,15 +594,66 @@ func (a *Area) Invited() []int {
 	return a.invited
 }
 
+// SetPassword sets the area's password. An empty password removes it.
+func (a *Area) SetPassword(password string) error {
+	var hash []byte
+	if password != "" {
+		var err error
+		hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+	}
+	a.mu.Lock()
+	a.password = hash
+	a.mu.Unlock()
+	return nil
+}
+
+// HasPassword returns whether the area has a password.
+func (a *Area) HasPassword() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.password != nil
+}
+
+// CheckPassword returns whether the given password matches the area's password.
+func (a *Area) CheckPassword(password string) bool {
+	a.mu.Lock()
+	hash := a.password
+	a.mu.Unlock()
+	if hash == nil {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// MaxPlayers returns the maximum number of players allowed in the area. 0 means unlimited.
+func (a *Area) MaxPlayers() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.data.Max_players
+}
+
+// IsFull returns whether the area has reached it's maximum number of players.
+func (a *Area) IsFull() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.data.Max_players > 0 && a.players >= a.data.Max_players
+}
+
 // Reset returns all area settings to their default values.
 func (a *Area) Reset() {
 	a.mu.Lock()
 	a.evidence = []string{}
 	a.invited = []int{}
+	a.password = nil
 	a.status = StatusIdle
 	a.lock = LockFree
 	a.cms = []int{}
 	a.last_msg = -1
+	a.music = defaultMusic(a.data.Ambience)
+	a.recentIC = nil
 	a.defhp = 10
 	a.prohp = 10
 	a.evi_mode = a.defaults.evi_mode
@@ -515,6 +667,33 @@ func (a *Area) Reset() {
 	a.tr.Index = 0
 	a.tr.State = TRIdle
 	a.tr.Testimony = []string{}
+	for i := range a.timers {
+		a.timers[i].Stop()
+	}
+	a.jukebox.SetEnabled(false)
+	a.jukebox.SetShuffle(false)
+	a.jukebox.SetEnabled(a.data.Jukebox)
+	a.reserved = make(map[int]int)
+	a.assigned = make(map[int]string)
+	a.judgeLck = false
+	a.caseS.End()
+	if a.jury != nil {
+		a.jury.Close()
+		a.jury = nil
+	}
+	a.jurors = []int{}
+	if a.poll != nil {
+		a.poll.Close()
+		a.poll = nil
+	}
+	if a.game != nil {
+		a.game.Stop()
+		a.game = nil
+	}
+	a.turns.Clear()
+	a.turns.SetStrict(false)
+	a.turns.SetTimeout(a.defaults.turn_timeout)
+	a.deck.Reset()
 	a.mu.Unlock()
 }
 
@@ -574,6 +753,350 @@ func (a *Area) SetDoc(s string) {
 	a.mu.Unlock()
 }
 
+// Music returns the music currently playing in the area, ordered by channel.
+func (a *Area) Music() []Music {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var l []Music
+	for c := 0; c < MusicChannels; c++ {
+		if m, ok := a.music[c]; ok {
+			l = append(l, m)
+		}
+	}
+	return l
+}
+
+// SetMusic sets the music playing on one of the area's channels. Music with no name clears the channel.
+func (a *Area) SetMusic(m Music) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if m.Name == "" {
+		delete(a.music, m.Channel)
+		return
+	}
+	a.music[m.Channel] = m
+}
+
+// Ambience returns the ambience currently playing in the area.
+func (a *Area) Ambience() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.music[AmbienceChannel].Name
+}
+
+// defaultMusic returns an area's initial music state.
+func defaultMusic(ambience string) map[int]Music {
+	m := make(map[int]Music)
+	if ambience != "" {
+		m[AmbienceChannel] = Music{Name: ambience, Channel: AmbienceChannel, Looping: true}
+	}
+	return m
+}
+
+// RecentIC returns the area's most recent IC messages, oldest first.
+func (a *Area) RecentIC() [][]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([][]string{}, a.recentIC...)
+}
+
+// AddRecentIC adds an IC message to the area's recent messages, keeping at most limit messages.
+func (a *Area) AddRecentIC(msg []string, limit int) {
+	if limit <= 0 {
+		return
+	}
+	a.mu.Lock()
+	a.recentIC = append(a.recentIC, append([]string{}, msg...))
+	if len(a.recentIC) > limit {
+		a.recentIC = a.recentIC[len(a.recentIC)-limit:]
+	}
+	a.mu.Unlock()
+}
+
+// Timer returns the area timer with the given ID, or nil if it does not exist.
+func (a *Area) Timer(id int) *Timer {
+	if id < 1 || id > AreaTimers {
+		return nil
+	}
+	return &a.timers[id-1]
+}
+
+// CharAllowed returns whether a character is permitted by the area's character allowlist and denylist.
+func (a *Area) CharAllowed(name string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.data.Allow_chars) > 0 && !sliceutil.ContainsString(a.data.Allow_chars, name) {
+		return false
+	}
+	return !sliceutil.ContainsString(a.data.Deny_chars, name)
+}
+
+// ModOnlyChar returns whether a character can only be used by moderators in the area.
+func (a *Area) ModOnlyChar(name string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return sliceutil.ContainsString(a.data.Mod_chars, name)
+}
+
+// Reserve reserves a character in the area for the given UID.
+func (a *Area) Reserve(char int, uid int) {
+	a.mu.Lock()
+	a.reserved[char] = uid
+	a.mu.Unlock()
+}
+
+// Unreserve removes a character's reservation. It returns false if the character was not reserved.
+func (a *Area) Unreserve(char int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.reserved[char]; !ok {
+		return false
+	}
+	delete(a.reserved, char)
+	return true
+}
+
+// ReservedBy returns the UID a character is reserved for, if any.
+func (a *Area) ReservedBy(char int) (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	uid, ok := a.reserved[char]
+	return uid, ok
+}
+
+// RemoveReservations removes all reservations for the given UID.
+func (a *Area) RemoveReservations(uid int) {
+	a.mu.Lock()
+	for char, u := range a.reserved {
+		if u == uid {
+			delete(a.reserved, char)
+		}
+	}
+	a.mu.Unlock()
+}
+
+// GameOver returns the area's penalty sequence, and whether it is enabled.
+func (a *Area) GameOver() (GameOver, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return GameOver{Animation: a.data.GO_anim, Music: a.data.GO_music, Message: a.data.GO_msg, Lock: a.data.GO_lock}, a.data.Game_over
+}
+
+// JudgeLocked returns whether the area's judge controls are locked.
+func (a *Area) JudgeLocked() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.judgeLck
+}
+
+// SetJudgeLocked sets whether the area's judge controls are locked.
+func (a *Area) SetJudgeLocked(b bool) {
+	a.mu.Lock()
+	a.judgeLck = b
+	a.mu.Unlock()
+}
+
+// RestrictPositions returns whether positions with assigned players are restricted to them.
+func (a *Area) RestrictPositions() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.data.Restrict_pos
+}
+
+// Positions are the positions available in the AO2 client.
+var Positions = []string{"def", "pro", "jud", "wit", "hld", "hlp", "jur", "sea"}
+
+// Assign assigns a UID to a position.
+func (a *Area) Assign(uid int, pos string) {
+	a.mu.Lock()
+	a.assigned[uid] = pos
+	a.mu.Unlock()
+}
+
+// Unassign removes a UID's position assignment. It returns false if the UID was not assigned.
+func (a *Area) Unassign(uid int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.assigned[uid]; !ok {
+		return false
+	}
+	delete(a.assigned, uid)
+	return true
+}
+
+// Assignment returns the position a UID is assigned to, if any.
+func (a *Area) Assignment(uid int) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pos, ok := a.assigned[uid]
+	return pos, ok
+}
+
+// PosHolders returns the UIDs assigned to a position.
+func (a *Area) PosHolders(pos string) []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var uids []int
+	for uid, p := range a.assigned {
+		if p == pos {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
+
+// Jury returns the area's jury vote, or nil if no jury vote has been held.
+func (a *Area) Jury() *vote.Vote {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.jury
+}
+
+// SetJury sets the area's jury vote, closing the previous one.
+func (a *Area) SetJury(v *vote.Vote) {
+	a.mu.Lock()
+	if a.jury != nil {
+		a.jury.Close()
+	}
+	a.jury = v
+	a.mu.Unlock()
+}
+
+// Poll returns the area's poll, or nil if no poll has been held.
+func (a *Area) Poll() *vote.Vote {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.poll
+}
+
+// SetPoll sets the area's poll, closing the previous one.
+func (a *Area) SetPoll(v *vote.Vote) {
+	a.mu.Lock()
+	if a.poll != nil {
+		a.poll.Close()
+	}
+	a.poll = v
+	a.mu.Unlock()
+}
+
+// Deck returns the area's deck of cards.
+func (a *Area) Deck() *Deck {
+	return &a.deck
+}
+
+// SetDefaultTurnTimeout sets the area's turn timeout, and the timeout it returns to when reset.
+func (a *Area) SetDefaultTurnTimeout(d time.Duration) {
+	a.mu.Lock()
+	a.defaults.turn_timeout = d
+	a.mu.Unlock()
+	a.turns.SetTimeout(d)
+}
+
+// Turns returns the area's turn order.
+func (a *Area) Turns() *TurnOrder {
+	return &a.turns
+}
+
+// Game returns the area's game, or nil if there is none.
+func (a *Area) Game() *game.Game {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.game
+}
+
+// SetGame sets the area's game, stopping the previous one.
+func (a *Area) SetGame(g *game.Game) {
+	a.mu.Lock()
+	if a.game != nil {
+		a.game.Stop()
+	}
+	a.game = g
+	a.mu.Unlock()
+}
+
+// AddJuror invites a UID to the area's jury. It returns false if the UID was already invited.
+func (a *Area) AddJuror(uid int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if sliceutil.ContainsInt(a.jurors, uid) {
+		return false
+	}
+	a.jurors = append(a.jurors, uid)
+	return true
+}
+
+// RemoveJuror removes a UID from the area's jury invitations. It returns false if the UID was not invited.
+func (a *Area) RemoveJuror(uid int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, id := range a.jurors {
+		if id == uid {
+			a.jurors = append(a.jurors[:i], a.jurors[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// IsJuror returns whether a UID is a juror, either by invitation or by being assigned to the jury position.
+func (a *Area) IsJuror(uid int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return sliceutil.ContainsInt(a.jurors, uid) || a.assigned[uid] == "jur"
+}
+
+// Jurors returns the UIDs of the area's jurors.
+func (a *Area) Jurors() []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	uids := append([]int{}, a.jurors...)
+	for uid, p := range a.assigned {
+		if p == "jur" && !sliceutil.ContainsInt(uids, uid) {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Ints(uids)
+	return uids
+}
+
+// MusicList returns the area's music list.
+func (a *Area) MusicList() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.musicL
+}
+
+// SetMusicList sets the area's music list.
+func (a *Area) SetMusicList(l []string) {
+	a.mu.Lock()
+	a.musicL = l
+	a.mu.Unlock()
+}
+
+// Backgrounds returns the area's background list.
+func (a *Area) Backgrounds() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.bgL
+}
+
+// SetBackgrounds sets the area's background list.
+func (a *Area) SetBackgrounds(l []string) {
+	a.mu.Lock()
+	a.bgL = l
+	a.mu.Unlock()
+}
+
+// Jukebox returns the area's jukebox.
+func (a *Area) Jukebox() *Jukebox {
+	return &a.jukebox
+}
+
+// Case returns the area's case session.
+func (a *Area) Case() *CaseSession {
+	return &a.caseS
+}
+
 // HasTestimony returns whether the area has a recorded testimony.
 func (a *Area) HasTestimony() bool {
 	a.mu.Lock()
@@ -639,3 +1162,20 @@ func (evimod EvidenceMode) String() string {
 	}
 	return ""
 }
+
+// String returns the string representation of the testimony recorder state.
+func (state TRState) String() string {
+	switch state {
+	case TRIdle:
+		return "idle"
+	case TRRecording:
+		return "recording"
+	case TRPlayback:
+		return "playback"
+	case TRUpdating:
+		return "updating"
+	case TRInserting:
+		return "inserting"
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Participant is a player who took part in a case.
+type Participant struct {
+	Uid       int
+	Character string
+	Positions []string
+}
+
+// CaseRecord is the record of a case session.
+type CaseRecord struct {
+	Title        string
+	Area         string
+	Host         string
+	Start        time.Time
+	End          time.Time
+	Participants []Participant
+	Evidence     []string
+	Testimonies  [][]string
+	HPChanges    []string
+	Events       []string
+	Verdict      string
+}
+
+// CaseSession records a case held in an area.
+type CaseSession struct {
+	mu     sync.Mutex
+	active bool
+	rec    CaseRecord
+}
+
+// Start starts a new case session. It returns false if a case is already in session.
+func (c *CaseSession) Start(area string, title string, host string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active {
+		return false
+	}
+	c.active = true
+	c.rec = CaseRecord{Title: title, Area: area, Host: host, Start: time.Now().UTC()}
+	return true
+}
+
+// Active returns whether a case is in session.
+func (c *CaseSession) Active() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active
+}
+
+// Title returns the title of the case in session.
+func (c *CaseSession) Title() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rec.Title
+}
+
+// Join records a participant and the position they spoke from.
+func (c *CaseSession) Join(uid int, char string, pos string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		return
+	}
+	for i, p := range c.rec.Participants {
+		if p.Uid == uid && p.Character == char {
+			for _, s := range p.Positions {
+				if s == pos {
+					return
+				}
+			}
+			c.rec.Participants[i].Positions = append(p.Positions, pos)
+			return
+		}
+	}
+	c.rec.Participants = append(c.rec.Participants, Participant{Uid: uid, Character: char, Positions: []string{pos}})
+}
+
+// Log adds a line to the case's transcript.
+func (c *CaseSession) Log(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		return
+	}
+	c.log(s)
+}
+
+// log adds a timestamped line to the transcript. The caller must hold the lock.
+func (c *CaseSession) log(s string) {
+	c.rec.Events = append(c.rec.Events, fmt.Sprintf("[%v] %v", time.Now().UTC().Format("15:04:05"), s))
+}
+
+// PresentEvidence records a piece of evidence being presented.
+func (c *CaseSession) PresentEvidence(by string, evi string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		return
+	}
+	found := false
+	for _, e := range c.rec.Evidence {
+		if e == evi {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.rec.Evidence = append(c.rec.Evidence, evi)
+	}
+	c.log(fmt.Sprintf("%v presented %v.", by, evi))
+}
+
+// AddTestimony records a testimony.
+func (c *CaseSession) AddTestimony(statements []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active || len(statements) == 0 {
+		return
+	}
+	c.rec.Testimonies = append(c.rec.Testimonies, append([]string{}, statements...))
+	c.log(fmt.Sprintf("Testimony %v was recorded.", len(c.rec.Testimonies)))
+}
+
+// ChangeHP records a change to a penalty bar.
+func (c *CaseSession) ChangeHP(by string, side string, value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		return
+	}
+	s := fmt.Sprintf("%v set the %v penalty bar to %v.", by, strings.ToLower(side), value)
+	c.rec.HPChanges = append(c.rec.HPChanges, s)
+	c.log(s)
+}
+
+// SetVerdict sets the verdict of the case.
+func (c *CaseSession) SetVerdict(v string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		return
+	}
+	c.rec.Verdict = v
+	c.log("Verdict: " + v)
+}
+
+// End ends the case in session, returning it's record. It returns false if no case is in session.
+func (c *CaseSession) End() (CaseRecord, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		return CaseRecord{}, false
+	}
+	c.active = false
+	c.rec.End = time.Now().UTC()
+	return c.rec, true
+}
+
+// Transcript returns a readable transcript of the case.
+func (r CaseRecord) Transcript() []string {
+	verdict := r.Verdict
+	if verdict == "" {
+		verdict = "None"
+	}
+	lines := []string{
+		"Case: " + r.Title,
+		"Area: " + r.Area,
+		"Host: " + r.Host,
+		"Started: " + r.Start.Format("2006-01-02 15:04:05 UTC"),
+		"Ended: " + r.End.Format("2006-01-02 15:04:05 UTC"),
+		"Verdict: " + verdict,
+		"",
+		"Participants:",
+	}
+	for _, p := range r.Participants {
+		lines = append(lines, fmt.Sprintf("  [%v] %v (%v)", p.Uid, p.Character, strings.Join(p.Positions, ", ")))
+	}
+	lines = append(lines, "", "Evidence presented:")
+	for _, e := range r.Evidence {
+		lines = append(lines, "  "+e)
+	}
+	lines = append(lines, "", "Penalty changes:")
+	for _, h := range r.HPChanges {
+		lines = append(lines, "  "+h)
+	}
+	for i, t := range r.Testimonies {
+		lines = append(lines, "", fmt.Sprintf("Testimony %v:", i+1))
+		for j, s := range t {
+			lines = append(lines, fmt.Sprintf("  %v. %v", j+1, s))
+		}
+	}
+	lines = append(lines, "", "Transcript:")
+	lines = append(lines, r.Events...)
+	return lines
+}
+
+// ParticipantNames returns a comma separated list of the case's participants.
+func (r CaseRecord) ParticipantNames() string {
+	var s []string
+	for _, p := range r.Participants {
+		s = append(s, fmt.Sprintf("%v (%v)", p.Character, strings.Join(p.Positions, ", ")))
+	}
+	return strings.Join(s, ", ")
+}
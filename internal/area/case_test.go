@@ -0,0 +1,68 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import "testing"
+
+func TestCaseSession(t *testing.T) {
+	var c CaseSession
+
+	// Nothing is recorded outside of a session.
+	c.Log("foo")
+	if _, ok := c.End(); ok {
+		t.Errorf("unexpected value for End(), got %t, want %t", ok, false)
+	}
+
+	if !c.Start("Courtroom", "Turnabout Test", "Mango") {
+		t.Errorf("unexpected value for Start(), got %t, want %t", false, true)
+	}
+	if c.Start("Courtroom", "Another Case", "Mango") {
+		t.Errorf("unexpected value for Start() while in session, got %t, want %t", true, false)
+	}
+
+	c.Join(0, "Phoenix", "def")
+	c.Join(0, "Phoenix", "wit")
+	c.Join(0, "Phoenix", "def")
+	c.Join(1, "Edgeworth", "pro")
+	c.PresentEvidence("Phoenix", "Badge")
+	c.PresentEvidence("Phoenix", "Badge")
+	c.ChangeHP("Judge", "Defense", 8)
+	c.SetVerdict("Not guilty")
+
+	r, ok := c.End()
+	if !ok {
+		t.Fatalf("unexpected value for End(), got %t, want %t", ok, true)
+	}
+	if c.Active() {
+		t.Errorf("unexpected value for Active() after End(), got %t, want %t", true, false)
+	}
+	if len(r.Participants) != 2 {
+		t.Errorf("unexpected number of participants, got %d, want %d", len(r.Participants), 2)
+	}
+	if len(r.Participants[0].Positions) != 2 {
+		t.Errorf("unexpected number of positions, got %d, want %d", len(r.Participants[0].Positions), 2)
+	}
+	if len(r.Evidence) != 1 {
+		t.Errorf("unexpected number of evidence, got %d, want %d", len(r.Evidence), 1)
+	}
+	if len(r.Events) != 4 {
+		t.Errorf("unexpected number of events, got %d, want %d", len(r.Events), 4)
+	}
+	if r.Verdict != "Not guilty" {
+		t.Errorf("unexpected value for Verdict, got %s, want %s", r.Verdict, "Not guilty")
+	}
+}
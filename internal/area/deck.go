@@ -0,0 +1,105 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import (
+	"sync"
+
+	"github.com/MangosArentLiterature/Athena/internal/dice"
+)
+
+// StandardDeck returns a standard 52 card deck.
+func StandardDeck() []string {
+	var cards []string
+	for _, suit := range []string{"♠", "♥", "♦", "♣"} {
+		for _, rank := range []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"} {
+			cards = append(cards, rank+suit)
+		}
+	}
+	return cards
+}
+
+// Deck is a deck of cards that can be shuffled and drawn from.
+type Deck struct {
+	mu    sync.Mutex
+	cards []string
+	pile  []string
+}
+
+// SetCards sets the cards in the deck, and resets it.
+func (d *Deck) SetCards(cards []string) {
+	d.mu.Lock()
+	d.cards = cards
+	d.reset()
+	d.mu.Unlock()
+}
+
+// Reset returns every drawn card to the deck, and shuffles it.
+func (d *Deck) Reset() {
+	d.mu.Lock()
+	d.reset()
+	d.mu.Unlock()
+}
+
+// reset returns every drawn card to the deck, and shuffles it. The caller must hold the lock.
+func (d *Deck) reset() {
+	if d.cards == nil {
+		d.cards = StandardDeck()
+	}
+	d.pile = append([]string{}, d.cards...)
+	d.shuffle()
+}
+
+// Shuffle shuffles the cards remaining in the deck.
+func (d *Deck) Shuffle() {
+	d.mu.Lock()
+	d.shuffle()
+	d.mu.Unlock()
+}
+
+// shuffle shuffles the cards remaining in the deck. The caller must hold the lock.
+func (d *Deck) shuffle() {
+	for i := len(d.pile) - 1; i > 0; i-- {
+		j := dice.Intn(i + 1)
+		d.pile[i], d.pile[j] = d.pile[j], d.pile[i]
+	}
+}
+
+// Draw draws up to n cards from the top of the deck.
+func (d *Deck) Draw(n int) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pile == nil {
+		d.reset()
+	}
+	if n > len(d.pile) {
+		n = len(d.pile)
+	}
+	drawn := append([]string{}, d.pile[:n]...)
+	d.pile = d.pile[n:]
+	return drawn
+}
+
+// Remaining returns the number of cards left in the deck, and the deck's full size.
+func (d *Deck) Remaining() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pile == nil {
+		d.reset()
+	}
+	return len(d.pile), len(d.cards)
+}
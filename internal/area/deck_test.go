@@ -0,0 +1,45 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import "testing"
+
+func TestDeck(t *testing.T) {
+	var d Deck
+	if left, size := d.Remaining(); left != 52 || size != 52 {
+		t.Errorf("unexpected size for standard deck, got %d/%d, want %d/%d", left, size, 52, 52)
+	}
+	seen := make(map[string]bool)
+	for _, c := range d.Draw(50) {
+		seen[c] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("unexpected number of unique cards drawn, got %d, want %d", len(seen), 50)
+	}
+	if drawn := d.Draw(5); len(drawn) != 2 {
+		t.Errorf("unexpected number of cards drawn from a nearly empty deck, got %d, want %d", len(drawn), 2)
+	}
+	d.Reset()
+	if left, _ := d.Remaining(); left != 52 {
+		t.Errorf("unexpected number of cards after Reset(), got %d, want %d", left, 52)
+	}
+
+	d.SetCards([]string{"Fool", "Magician"})
+	if left, size := d.Remaining(); left != 2 || size != 2 {
+		t.Errorf("unexpected size for custom deck, got %d/%d, want %d/%d", left, size, 2, 2)
+	}
+}
@@ -0,0 +1,105 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import "sync"
+
+// Hub is a named group of areas with its own area list.
+// The first area in a hub is it's lobby.
+type Hub struct {
+	data  HubData
+	mu    sync.Mutex
+	areas []*Area
+}
+
+type HubData struct {
+	Name       string            `toml:"name"`
+	Moderators map[string]string `toml:"moderators"`
+}
+
+// NewHub returns a new hub.
+func NewHub(data HubData) *Hub {
+	return &Hub{data: data}
+}
+
+// Name returns the hub's name.
+func (h *Hub) Name() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.data.Name
+}
+
+// Moderators returns the hub's moderators, mapping usernames to role names.
+func (h *Hub) Moderators() map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.data.Moderators
+}
+
+// Areas returns the areas in the hub.
+func (h *Hub) Areas() []*Area {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*Area{}, h.areas...)
+}
+
+// AddArea adds an area to the hub.
+func (h *Hub) AddArea(a *Area) {
+	h.mu.Lock()
+	h.areas = append(h.areas, a)
+	h.mu.Unlock()
+}
+
+// RemoveArea removes an area from the hub.
+func (h *Hub) RemoveArea(a *Area) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, x := range h.areas {
+		if x == a {
+			h.areas = append(h.areas[:i], h.areas[i+1:]...)
+			return
+		}
+	}
+}
+
+// HasArea returns whether the given area is in the hub.
+func (h *Hub) HasArea(a *Area) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, x := range h.areas {
+		if x == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Lobby returns the hub's lobby.
+func (h *Hub) Lobby() *Area {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.areas[0]
+}
+
+// AreaNames returns the names of the areas in the hub.
+func (h *Hub) AreaNames() []string {
+	var names []string
+	for _, a := range h.Areas() {
+		names = append(names, a.Name())
+	}
+	return names
+}
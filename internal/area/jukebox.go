@@ -0,0 +1,161 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Song is a song requested in an area's jukebox.
+type Song struct {
+	Name      string
+	Length    time.Duration
+	Requester int
+}
+
+// Jukebox plays queued songs in an area one after another.
+type Jukebox struct {
+	mu      sync.Mutex
+	enabled bool
+	shuffle bool
+	queue   []Song
+	current *Song
+	timer   *time.Timer
+	gen     int
+	rng     *rand.Rand
+}
+
+// Enabled returns whether the jukebox is enabled.
+func (j *Jukebox) Enabled() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.enabled
+}
+
+// SetEnabled enables or disables the jukebox. Disabling the jukebox clears it's queue.
+func (j *Jukebox) SetEnabled(b bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.enabled = b
+	if !b {
+		j.halt()
+		j.queue = nil
+		j.current = nil
+	}
+}
+
+// Shuffle returns whether the jukebox plays songs in a random order.
+func (j *Jukebox) Shuffle() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.shuffle
+}
+
+// SetShuffle sets whether the jukebox plays songs in a random order.
+func (j *Jukebox) SetShuffle(b bool) {
+	j.mu.Lock()
+	j.shuffle = b
+	j.mu.Unlock()
+}
+
+// Add adds a song to the queue. It returns true if the jukebox is idle, and should be advanced with Next.
+func (j *Jukebox) Add(s Song) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.queue = append(j.queue, s)
+	return j.current == nil
+}
+
+// Queue returns the songs waiting to be played.
+func (j *Jukebox) Queue() []Song {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]Song{}, j.queue...)
+}
+
+// Current returns the song currently playing, if any.
+func (j *Jukebox) Current() (Song, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.current == nil {
+		return Song{}, false
+	}
+	return *j.current, true
+}
+
+// Clear empties the queue.
+func (j *Jukebox) Clear() {
+	j.mu.Lock()
+	j.queue = nil
+	j.mu.Unlock()
+}
+
+// Stop stops the current song without playing the next one, keeping the queue. It returns whether a song was playing.
+// The jukebox resumes when it is next advanced, or when a song is added.
+func (j *Jukebox) Stop() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.halt()
+	playing := j.current != nil
+	j.current = nil
+	return playing
+}
+
+// Next plays the next song in the queue with the given function, and schedules the song after it.
+// It returns false if the queue is empty.
+func (j *Jukebox) Next(play func(Song)) bool {
+	j.mu.Lock()
+	j.halt()
+	if !j.enabled || len(j.queue) == 0 {
+		j.current = nil
+		j.mu.Unlock()
+		return false
+	}
+	i := 0
+	if j.shuffle {
+		if j.rng == nil {
+			j.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+		i = j.rng.Intn(len(j.queue))
+	}
+	s := j.queue[i]
+	j.queue = append(j.queue[:i], j.queue[i+1:]...)
+	j.current = &s
+	gen := j.gen
+	j.timer = time.AfterFunc(s.Length, func() {
+		j.mu.Lock()
+		stale := j.gen != gen
+		j.mu.Unlock()
+		if !stale {
+			j.Next(play)
+		}
+	})
+	j.mu.Unlock()
+	play(s)
+	return true
+}
+
+// halt cancels the pending advance to the next song. The caller must hold the jukebox's lock.
+func (j *Jukebox) halt() {
+	if j.timer != nil {
+		j.timer.Stop()
+		j.timer = nil
+	}
+	j.gen++
+}
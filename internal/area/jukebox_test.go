@@ -0,0 +1,71 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJukebox(t *testing.T) {
+	var j Jukebox
+	j.SetEnabled(true)
+	played := make(chan string, 3)
+	play := func(s Song) { played <- s.Name }
+
+	// The first song added to an idle jukebox should be played straight away.
+	if !j.Add(Song{Name: "a.opus", Length: 10 * time.Millisecond}) {
+		t.Errorf("adding song to idle jukebox, got %t, want %t", false, true)
+	}
+	j.Next(play)
+	if j.Add(Song{Name: "b.opus", Length: time.Hour}) {
+		t.Errorf("adding song to playing jukebox, got %t, want %t", true, false)
+	}
+
+	// Songs play in order once the previous song has finished.
+	for _, want := range []string{"a.opus", "b.opus"} {
+		select {
+		case got := <-played:
+			if got != want {
+				t.Errorf("unexpected song played, got %v, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("song %v was not played", want)
+		}
+	}
+	if s, ok := j.Current(); !ok || s.Name != "b.opus" {
+		t.Errorf("unexpected current song, got %v, want %v", s.Name, "b.opus")
+	}
+
+	// Stopping the jukebox keeps the queue, and adding a song resumes it.
+	j.Add(Song{Name: "c.opus", Length: time.Hour})
+	if !j.Stop() {
+		t.Errorf("stopping playing jukebox, got %t, want %t", false, true)
+	}
+	if len(j.Queue()) != 1 {
+		t.Errorf("unexpected queue length after stopping, got %d, want %d", len(j.Queue()), 1)
+	}
+	if !j.Add(Song{Name: "d.opus", Length: time.Hour}) {
+		t.Errorf("adding song to stopped jukebox, got %t, want %t", false, true)
+	}
+
+	// Disabling the jukebox stops it.
+	j.SetEnabled(false)
+	if _, ok := j.Current(); ok {
+		t.Errorf("disabled jukebox is still playing")
+	}
+}
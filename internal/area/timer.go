@@ -0,0 +1,130 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import (
+	"sync"
+	"time"
+)
+
+// Timer is a countdown timer, displayed to clients with the TI packet.
+type Timer struct {
+	mu        sync.Mutex
+	remaining time.Duration
+	started   time.Time
+	running   bool
+	visible   bool
+	message   string
+	timer     *time.Timer
+	gen       int
+}
+
+// Set stops the timer and sets it to the given duration, with an optional message sent on expiry.
+func (t *Timer) Set(d time.Duration, msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.halt()
+	t.remaining = d
+	t.running = false
+	t.visible = true
+	t.message = msg
+}
+
+// Start starts or resumes the timer. onExpire is called with the timer's message when it runs out.
+// It returns false if the timer is already running or has no time remaining.
+func (t *Timer) Start(onExpire func(msg string)) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running || t.remaining <= 0 {
+		return false
+	}
+	t.running = true
+	t.visible = true
+	t.started = time.Now()
+	gen := t.gen
+	t.timer = time.AfterFunc(t.remaining, func() {
+		t.mu.Lock()
+		if t.gen != gen {
+			t.mu.Unlock()
+			return
+		}
+		t.remaining = 0
+		t.running = false
+		t.timer = nil
+		msg := t.message
+		t.mu.Unlock()
+		onExpire(msg)
+	})
+	return true
+}
+
+// Pause pauses the timer. It returns false if the timer is not running.
+func (t *Timer) Pause() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.running {
+		return false
+	}
+	t.halt()
+	t.remaining -= time.Since(t.started)
+	if t.remaining < 0 {
+		t.remaining = 0
+	}
+	t.running = false
+	return true
+}
+
+// Stop stops and hides the timer.
+func (t *Timer) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.halt()
+	t.remaining = 0
+	t.running = false
+	t.visible = false
+	t.message = ""
+}
+
+// SetVisible sets whether the timer is shown to clients.
+func (t *Timer) SetVisible(b bool) {
+	t.mu.Lock()
+	t.visible = b
+	t.mu.Unlock()
+}
+
+// State returns the timer's remaining time, and whether it is running and visible.
+func (t *Timer) State() (time.Duration, bool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	remaining := t.remaining
+	if t.running {
+		remaining -= time.Since(t.started)
+		if remaining < 0 {
+			remaining = 0
+		}
+	}
+	return remaining, t.running, t.visible
+}
+
+// halt cancels the timer's pending expiry. The caller must hold the timer's lock.
+func (t *Timer) halt() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.gen++
+}
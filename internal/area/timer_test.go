@@ -0,0 +1,67 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimer(t *testing.T) {
+	var timer Timer
+	expired := make(chan string, 1)
+	onExpire := func(msg string) { expired <- msg }
+
+	// A timer with no time cannot be started.
+	if timer.Start(onExpire) {
+		t.Errorf("starting empty timer, got %t, want %t", true, false)
+	}
+
+	timer.Set(time.Hour, "")
+	if !timer.Start(onExpire) {
+		t.Errorf("starting timer, got %t, want %t", false, true)
+	}
+	if !timer.Pause() {
+		t.Errorf("pausing timer, got %t, want %t", false, true)
+	}
+	remaining, running, visible := timer.State()
+	if remaining <= 0 || remaining > time.Hour || running || !visible {
+		t.Errorf("unexpected timer state, got (%v, %t, %t)", remaining, running, visible)
+	}
+
+	// The timer expires and sends it's message.
+	timer.Set(time.Millisecond, "Time's up!")
+	timer.Start(onExpire)
+	select {
+	case msg := <-expired:
+		if msg != "Time's up!" {
+			t.Errorf("unexpected expiry message, got %q, want %q", msg, "Time's up!")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("timer did not expire")
+	}
+
+	// A stopped timer never expires.
+	timer.Set(10*time.Millisecond, "")
+	timer.Start(onExpire)
+	timer.Stop()
+	select {
+	case <-expired:
+		t.Errorf("stopped timer expired")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
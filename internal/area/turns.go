@@ -0,0 +1,199 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import (
+	"sync"
+	"time"
+)
+
+// TurnOrder tracks whose turn it is in an area.
+type TurnOrder struct {
+	mu      sync.Mutex
+	order   []int
+	index   int
+	started bool
+	strict  bool
+	timeout time.Duration
+	idle    func(uid int, stalled bool)
+	skips   int // Consecutive turns skipped for idleness.
+	timer   *time.Timer
+	gen     int
+}
+
+// Add adds a UID to the end of the turn order. It returns false if the UID is already in it.
+func (t *TurnOrder) Add(uid int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, u := range t.order {
+		if u == uid {
+			return false
+		}
+	}
+	t.order = append(t.order, uid)
+	return true
+}
+
+// Remove removes a UID from the turn order. It returns true if it was that UID's turn, in which case
+// the turn should be passed on with Next.
+func (t *TurnOrder) Remove(uid int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, u := range t.order {
+		if u != uid {
+			continue
+		}
+		t.order = append(t.order[:i], t.order[i+1:]...)
+		current := t.started && i == t.index
+		if t.started && i <= t.index {
+			t.index--
+		}
+		if current {
+			t.halt()
+		}
+		if len(t.order) == 0 {
+			t.started = false
+		}
+		return current && t.started
+	}
+	return false
+}
+
+// Order returns the UIDs in the turn order.
+func (t *TurnOrder) Order() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]int{}, t.order...)
+}
+
+// Current returns the UID whose turn it is, if the turn order has started.
+func (t *TurnOrder) Current() (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started || t.index < 0 || t.index >= len(t.order) {
+		return -1, false
+	}
+	return t.order[t.index], true
+}
+
+// Next passes the turn to the next UID in the order, returning it. It returns false if the order is empty.
+// If the turn order has a timeout, idle is called with the UID if they do not act before it expires.
+// stalled is true once a full round of turns has been skipped in a row, in which case the turn should not be passed on automatically.
+func (t *TurnOrder) Next(idle func(uid int, stalled bool)) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.halt()
+	if len(t.order) == 0 {
+		t.started = false
+		return -1, false
+	}
+	if !t.started {
+		t.started = true
+		t.index = 0
+	} else {
+		t.index = (t.index + 1) % len(t.order)
+	}
+	t.idle = idle
+	t.schedule()
+	return t.order[t.index], true
+}
+
+// Touch restarts the idle timeout if it is the given UID's turn.
+func (t *TurnOrder) Touch(uid int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started || t.index < 0 || t.index >= len(t.order) || t.order[t.index] != uid {
+		return
+	}
+	t.skips = 0
+	t.halt()
+	t.schedule()
+}
+
+// Strict returns whether only the current turn holder may speak IC.
+func (t *TurnOrder) Strict() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.strict
+}
+
+// SetStrict sets whether only the current turn holder may speak IC.
+func (t *TurnOrder) SetStrict(b bool) {
+	t.mu.Lock()
+	t.strict = b
+	t.mu.Unlock()
+}
+
+// Timeout returns how long a turn holder may stay idle before their turn is skipped.
+func (t *TurnOrder) Timeout() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timeout
+}
+
+// SetTimeout sets how long a turn holder may stay idle before their turn is skipped. A timeout of 0 disables skipping.
+func (t *TurnOrder) SetTimeout(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timeout = d
+	if t.started {
+		t.halt()
+		t.schedule()
+	}
+}
+
+// Clear empties the turn order.
+func (t *TurnOrder) Clear() {
+	t.mu.Lock()
+	t.halt()
+	t.order = nil
+	t.started = false
+	t.skips = 0
+	t.mu.Unlock()
+}
+
+// schedule starts the current turn holder's idle timeout. The caller must hold the lock.
+func (t *TurnOrder) schedule() {
+	if t.timeout <= 0 || t.idle == nil {
+		return
+	}
+	gen, uid, idle := t.gen, t.order[t.index], t.idle
+	t.timer = time.AfterFunc(t.timeout, func() {
+		t.mu.Lock()
+		stale := t.gen != gen
+		var stalled bool
+		if !stale {
+			t.skips++
+			if stalled = t.skips >= len(t.order); stalled {
+				t.skips = 0
+			}
+		}
+		t.mu.Unlock()
+		if !stale {
+			idle(uid, stalled)
+		}
+	})
+}
+
+// halt cancels the pending idle timeout. The caller must hold the lock.
+func (t *TurnOrder) halt() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.gen++
+}
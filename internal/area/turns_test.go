@@ -0,0 +1,76 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package area
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTurnOrder(t *testing.T) {
+	var turns TurnOrder
+	for _, uid := range []int{1, 2, 3} {
+		turns.Add(uid)
+	}
+	if turns.Add(1) {
+		t.Errorf("unexpected value for Add() of a duplicate UID, got %t, want %t", true, false)
+	}
+	if _, ok := turns.Current(); ok {
+		t.Errorf("unexpected value for Current() before starting, got %t, want %t", ok, false)
+	}
+
+	if uid, _ := turns.Next(nil); uid != 1 {
+		t.Errorf("unexpected value for Next(), got %d, want %d", uid, 1)
+	}
+	turns.Next(nil)
+
+	// Removing the current player should pass the turn to the player after them.
+	if !turns.Remove(2) {
+		t.Errorf("unexpected value for Remove() of the current player, got %t, want %t", false, true)
+	}
+	if uid, _ := turns.Next(nil); uid != 3 {
+		t.Errorf("unexpected value for Next() after Remove(), got %d, want %d", uid, 3)
+	}
+	if uid, _ := turns.Next(nil); uid != 1 {
+		t.Errorf("unexpected value for Next() wrapping around, got %d, want %d", uid, 1)
+	}
+
+	// An idle player is skipped after the timeout.
+	skipped := make(chan bool, 1)
+	var idle func(uid int, stalled bool)
+	idle = func(uid int, stalled bool) {
+		skipped <- stalled
+		if !stalled {
+			turns.Next(idle)
+		}
+	}
+	turns.SetTimeout(10 * time.Millisecond)
+	turns.Next(idle)
+
+	// Once everyone has been skipped in a row, the order stalls instead of skipping forever.
+	for i, want := range []bool{false, true} {
+		select {
+		case stalled := <-skipped:
+			if stalled != want {
+				t.Errorf("unexpected stalled value for skip %d, got %t, want %t", i+1, stalled, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("idle player was not skipped")
+		}
+	}
+	turns.Clear()
+}
@@ -0,0 +1,151 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package athena
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MangosArentLiterature/Athena/internal/area"
+)
+
+// caseRoles are the roles a case can announce for, in the order used by CASEA and SETCASE.
+var caseRoles = [5]string{"defense", "prosecution", "judge", "jurors", "stenographer"}
+
+// caseListing is a case announced with CASEA, which stays listed until it expires or it's area empties.
+type caseListing struct {
+	title   string
+	area    *area.Area
+	cm      string
+	uid     int
+	roles   [5]bool
+	expires time.Time
+}
+
+var (
+	caseListings []caseListing
+	lastCaseAnn  = make(map[string]time.Time)
+	listingsMu   sync.Mutex
+)
+
+// addCaseListing adds a case listing announced by the given IPID, replacing any existing listing for it's area.
+// If the IPID announced a case too recently, it returns false and the time until it can announce again.
+func addCaseListing(ipid string, l caseListing) (time.Duration, bool) {
+	listingsMu.Lock()
+	defer listingsMu.Unlock()
+	now := time.Now().UTC()
+	if wait := lastCaseAnn[ipid].Add(caseCooldown).Sub(now); wait > 0 {
+		return wait, false
+	}
+	lastCaseAnn[ipid] = now
+	pruneCaseListings()
+	for i, old := range caseListings {
+		if old.area == l.area {
+			caseListings = append(caseListings[:i], caseListings[i+1:]...)
+			break
+		}
+	}
+	l.expires = now.Add(caseExpiry)
+	caseListings = append(caseListings, l)
+	return 0, true
+}
+
+// removeCaseListing removes an area's case listing.
+func removeCaseListing(a *area.Area) {
+	listingsMu.Lock()
+	defer listingsMu.Unlock()
+	for i, l := range caseListings {
+		if l.area == a {
+			caseListings = append(caseListings[:i], caseListings[i+1:]...)
+			return
+		}
+	}
+}
+
+// getCaseListings returns the current case listings.
+func getCaseListings() []caseListing {
+	listingsMu.Lock()
+	defer listingsMu.Unlock()
+	pruneCaseListings()
+	return append([]caseListing{}, caseListings...)
+}
+
+// pruneCaseListings removes expired case listings. The caller must hold listingsMu.
+func pruneCaseListings() {
+	now := time.Now().UTC()
+	kept := caseListings[:0]
+	for _, l := range caseListings {
+		if l.expires.After(now) {
+			kept = append(kept, l)
+		}
+	}
+	caseListings = kept
+	for ipid, t := range lastCaseAnn {
+		if now.Sub(t) >= caseCooldown {
+			delete(lastCaseAnn, ipid)
+		}
+	}
+}
+
+// packet returns the CASEA packet announcing the listing.
+func (l caseListing) packet() string {
+	roles := make([]string, len(l.roles))
+	for i, r := range l.roles {
+		roles[i] = "0"
+		if r {
+			roles[i] = "1"
+		}
+	}
+	// Due to a bug, old client versions require this packet to have an extra arg.
+	return fmt.Sprintf("CASEA#CASE ANNOUNCEMENT: %v in %v needs players for %v#%v#1#%%",
+		l.cm, l.area.Name(), l.title, strings.Join(roles, "#"))
+}
+
+// wants returns whether a set of case preferences match any of the listing's roles.
+func (l caseListing) wants(prefs [5]bool) bool {
+	for i, r := range l.roles {
+		if r && prefs[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// neededRoles returns the names of the roles the listing needs.
+func (l caseListing) neededRoles() string {
+	var s []string
+	for i, r := range l.roles {
+		if r {
+			s = append(s, caseRoles[i])
+		}
+	}
+	if len(s) == 0 {
+		return "anyone"
+	}
+	return strings.Join(s, ", ")
+}
+
+// sendCaseListings announces current case listings matching the given case preferences to a client.
+func sendCaseListings(client *Client, prefs [5]bool) {
+	for _, l := range getCaseListings() {
+		if l.area != client.Area() && l.wants(prefs) {
+			client.write(l.packet())
+		}
+	}
+}
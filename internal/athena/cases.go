@@ -0,0 +1,106 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package athena
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MangosArentLiterature/Athena/internal/area"
+	"github.com/MangosArentLiterature/Athena/internal/db"
+	"github.com/MangosArentLiterature/Athena/internal/logger"
+)
+
+// Handles /case
+func cmdCase(client *Client, args []string, usage string) {
+	c := client.Area().Case()
+	switch args[0] {
+	case "start":
+		if len(args) < 2 {
+			client.SendServerMessage("Not enough arguments.\n" + usage)
+			return
+		}
+		title := strings.Join(args[1:], " ")
+		if !c.Start(client.Area().Name(), title, client.OOCName()) {
+			client.SendServerMessage(fmt.Sprintf("A case is already in session: %v.", c.Title()))
+			return
+		}
+		client.Area().SetStatus(area.StatusCasing)
+		sendStatusArup()
+		updateAdvert()
+		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v started the case: %v.", client.OOCName(), title))
+		addToBuffer(client, "CMD", fmt.Sprintf("Started the case: %v.", title), false)
+	case "end":
+		title := c.Title()
+		if len(args) > 1 {
+			c.SetVerdict(strings.Join(args[1:], " "))
+		}
+		fname, ok := endCase(client.Area())
+		if !ok {
+			client.SendServerMessage("There is no case in session.")
+			return
+		}
+		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v ended the case: %v.", client.OOCName(), title))
+		client.SendServerMessage(fmt.Sprintf("Case transcript saved to %v.", fname))
+		addToBuffer(client, "CMD", fmt.Sprintf("Ended the case: %v.", title), false)
+	default:
+		client.SendServerMessage("Invalid command.\n" + usage)
+	}
+}
+
+// Handles /cases
+func cmdCases(client *Client, args []string, usage string) {
+	if len(args) == 0 {
+		listings := getCaseListings()
+		if len(listings) == 0 {
+			client.SendServerMessage("No cases are looking for players.")
+			return
+		}
+		out := "\nCase Listings\n----------"
+		for _, l := range listings {
+			out += fmt.Sprintf("\n%v\nArea: %v | CM: %v [%v]\nNeeds: %v | Expires in %v\n",
+				l.title, l.area.Name(), l.cm, l.uid, l.neededRoles(), time.Until(l.expires).Round(time.Minute))
+		}
+		client.SendServerMessage(out)
+		return
+	}
+	if args[0] != "history" {
+		client.SendServerMessage("Invalid command.\n" + usage)
+		return
+	}
+	cases, err := db.GetRecentCases(10)
+	if err != nil {
+		logger.LogError(err.Error())
+		client.SendServerMessage("An unexpected error occured.")
+		return
+	}
+	if len(cases) == 0 {
+		client.SendServerMessage("No cases have been recorded.")
+		return
+	}
+	out := "\nRecent Cases\n----------"
+	for _, c := range cases {
+		verdict := c.Verdict
+		if verdict == "" {
+			verdict = "None"
+		}
+		out += fmt.Sprintf("\n#%v: %v (%v)\nHost: %v | Ended: %v | Verdict: %v\nParticipants: %v\n",
+			c.Id, c.Title, c.Area, c.Host, time.Unix(c.End, 0).UTC().Format("02 Jan 2006 15:04 MST"), verdict, c.Participants)
+	}
+	client.SendServerMessage(out)
+}
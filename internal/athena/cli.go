@@ -77,7 +77,7 @@ func ListenInput() {
 				logger.LogInfo("Not enough arguments for command getlog. Usage: getlog <area>.")
 				break
 			}
-			for _, a := range areas {
+			for _, a := range getAreas() {
 				if a.Name() == cmd[1] {
 					logger.LogInfo(strings.Join(a.Buffer(), "\n"))
 				}
@@ -68,6 +68,7 @@ type Client struct {
 	oocName       string
 	lastmsg       string
 	perms         uint64
+	areaPerms     map[*area.Area]uint64
 	authenticated bool
 	mod_name      string
 	pos           string
@@ -103,6 +104,7 @@ func (client *Client) HandleClient() {
 	if mc >= config.MCLimit && config.MCLimit != 0 {
 		client.SendPacket("BD", "You have reached the server's multiclient limit.")
 		client.conn.Close()
+		postRaidAlert(client.Ipid(), "A client reached the multiclient limit.")
 		return
 	}
 
@@ -169,7 +171,16 @@ func (client *Client) clientCleanup() {
 	if client.Uid() != -1 {
 		logger.LogInfof("Client (IPID:%v UID:%v) left the server", client.ipid, client.Uid())
 
-		if client.Area().PlayerCount() <= 1 {
+		leaveGame(client)
+		if client.Area().Turns().Remove(client.Uid()) {
+			advanceTurn(client.Area())
+		}
+		last, temp := client.Area().PlayerCount() <= 1, isTempArea(client.Area())
+		if last {
+			endCase(client.Area())
+			removeCaseListing(client.Area())
+		}
+		if last && !temp {
 			client.Area().Reset()
 			sendLockArup()
 			sendStatusArup()
@@ -178,18 +189,22 @@ func (client *Client) clientCleanup() {
 			client.Area().RemoveCM(client.Uid())
 			sendCMArup()
 		}
-		for _, a := range areas {
+		for _, a := range getAreas() {
 			if a.Lock() != area.LockFree {
 				a.RemoveInvited(client.Uid())
 			}
+			a.RemoveReservations(client.Uid())
+			a.RemoveJuror(client.Uid())
+			a.Unassign(client.Uid())
 		}
 		uids.ReleaseUid(client.Uid())
 		players.RemovePlayer()
-		if config.Advertise {
-			updatePlayers <- players.GetPlayerCount()
-		}
 		client.Area().RemoveChar(client.CharID())
+		if last && temp {
+			removeTempArea(client.Area())
+		}
 		sendPlayerArup()
+		updateAdvert()
 	}
 	client.conn.Close()
 	clients.RemoveClient(client)
@@ -322,6 +337,56 @@ func (client *Client) SetPerms(perms uint64) {
 	client.mu.Unlock()
 }
 
+// AreaPerms returns the client's area-scoped permissions.
+func (client *Client) AreaPerms() map[*area.Area]uint64 {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.areaPerms
+}
+
+// SetAreaPerms sets the client's area-scoped permissions.
+func (client *Client) SetAreaPerms(perms map[*area.Area]uint64) {
+	client.mu.Lock()
+	client.areaPerms = perms
+	client.mu.Unlock()
+}
+
+// PermsIn returns the client's permissions in the given area, including any area-scoped permissions.
+func (client *Client) PermsIn(a *area.Area) uint64 {
+	client.mu.Lock()
+	perms, areaPerms := client.perms, client.areaPerms[a]
+	auth, name := client.authenticated, client.mod_name
+	client.mu.Unlock()
+	if auth {
+		areaPerms |= getHubPerms(getHub(a), name)
+	}
+	return perms | areaPerms&permissions.AreaScoped
+}
+
+// Hub returns the hub of the client's current area.
+func (client *Client) Hub() *area.Hub {
+	return getHub(client.Area())
+}
+
+// HasGlobalPermission returns whether the client has the given permission server-wide, ignoring area-scoped permissions.
+func (client *Client) HasGlobalPermission(perm uint64) bool {
+	return permissions.HasPermission(client.Perms(), perm)
+}
+
+// HasPermission returns whether the client has the given permission in it's current area.
+func (client *Client) HasPermission(perm uint64) bool {
+	return permissions.HasPermission(client.PermsIn(client.Area()), perm)
+}
+
+// CanModerate returns whether the client can use the given permission on a target client.
+// Area-scoped permissions only apply to targets in the same area as the client.
+func (client *Client) CanModerate(target *Client, perm uint64) bool {
+	if permissions.HasPermission(client.Perms(), perm) {
+		return true
+	}
+	return target.Area() == client.Area() && client.HasPermission(perm)
+}
+
 // Authenticated returns whether the client is logged in as a moderator.
 func (client *Client) Authenticated() bool {
 	client.mu.Lock()
@@ -416,10 +481,11 @@ func (client *Client) SetPairWantedID(id int) {
 // RemoveAuth logs a client out as moderator.
 func (client *Client) RemoveAuth() {
 	client.mu.Lock()
-	client.authenticated, client.perms, client.mod_name = false, 0, ""
+	client.authenticated, client.perms, client.areaPerms, client.mod_name = false, 0, nil, ""
 	client.mu.Unlock()
 	client.SendServerMessage("Logged out as moderator.")
 	client.SendPacket("AUTH", "-1")
+	client.sendCharsCheck()
 }
 
 // CheckBanned returns if a client is currently banned.
@@ -453,53 +519,134 @@ func (client *Client) CheckBanned(by db.BanLookup) {
 	}
 }
 
-// JoinArea adds a client to an area.
-func (client *Client) JoinArea(area *area.Area) {
+// JoinArea adds a client to an area, returning false if the area has since been removed.
+func (client *Client) JoinArea(area *area.Area) bool {
+	if !addToArea(area, client) {
+		return false
+	}
 	client.SetArea(area)
-	area.AddChar(client.CharID())
-	def, pro := area.HP()
-	client.SendPacket("LE", areas[0].Evidence()...)
-	client.SendPacket("CharsCheck", area.Taken()...)
+	client.sendAreaState()
+	sendPlayerArup()
+	return true
+}
+
+// sendAreaState sends a client the full state of it's current area.
+func (client *Client) sendAreaState() {
+	a := client.Area()
+	def, pro := a.HP()
+	client.SendPacket("LE", a.Evidence()...)
+	client.sendCharsCheck()
 	client.SendPacket("HP", "1", strconv.Itoa(def))
 	client.SendPacket("HP", "2", strconv.Itoa(pro))
-	client.SendPacket("BN", area.Background())
-	sendPlayerArup()
+	client.SendPacket("BN", a.Background())
+	sendTimer(client.SendPacket, 0, &globalTimer)
+	for id := 1; id <= area.AreaTimers; id++ {
+		sendTimer(client.SendPacket, id, a.Timer(id))
+	}
+	playing := make(map[int]bool)
+	for _, m := range a.Music() {
+		playing[m.Channel] = true
+		client.SendPacket("MC", musicPacket(m, -1, "")...)
+	}
+	for c := 1; c < area.MusicChannels; c++ { // Stop any extra channels left playing from the previous area.
+		if !playing[c] {
+			client.SendPacket("MC", musicPacket(area.Music{Name: "~stop.mp3", Channel: c}, -1, "")...)
+		}
+	}
+	if a.TstState() != area.TRIdle {
+		client.SendServerMessage(fmt.Sprintf("Testimony in this area is currently in %v mode.", a.TstState().String()))
+	}
+	if a.Doc() != "" {
+		client.SendServerMessage(fmt.Sprintf("This area's doc: %v", a.Doc()))
+	}
+}
+
+// sendRecentIC replays the recent IC messages of the client's area.
+// This must be sent after DONE, as the client drops IC messages while in character select.
+func (client *Client) sendRecentIC() {
+	for _, msg := range client.Area().RecentIC() {
+		client.SendPacket("MS", msg...)
+	}
 }
 
 // ChangeArea changes the client's current area.
-func (client *Client) ChangeArea(a *area.Area) bool {
-	if a.Lock() == area.LockLocked &&
-		!sliceutil.ContainsInt(a.Invited(), client.Uid()) &&
-		!permissions.HasPermission(client.Perms(), permissions.PermissionField["BYPASS_LOCK"]) {
-		return false
+func (client *Client) ChangeArea(a *area.Area) error {
+	bypass := permissions.HasPermission(client.PermsIn(a), permissions.PermissionField["BYPASS_LOCK"])
+	if a.Lock() == area.LockLocked && !sliceutil.ContainsInt(a.Invited(), client.Uid()) && !bypass {
+		if a.HasPassword() {
+			return fmt.Errorf("that area is password protected")
+		}
+		return fmt.Errorf("you are not invited to that area")
+	}
+	if a.IsFull() && !bypass {
+		return fmt.Errorf("that area is full")
 	}
 	addToBuffer(client, "AREA", "Left area.", false)
-	if client.Area().PlayerCount() <= 1 {
+	leaveGame(client)
+	if client.Area().Turns().Remove(client.Uid()) {
+		advanceTurn(client.Area())
+	}
+	client.Area().RemoveJuror(client.Uid())
+	oldArea := client.Area()
+	last := oldArea.PlayerCount() <= 1
+	removeOld := last && isTempArea(oldArea)
+	if last {
+		endCase(oldArea)
+		removeCaseListing(oldArea)
+	}
+	if last && !removeOld {
 		client.Area().Reset()
 		sendLockArup()
 		sendStatusArup()
 		sendCMArup()
+		updateAdvert()
 	} else if client.Area().HasCM(client.Uid()) {
 		client.Area().RemoveCM(client.Uid())
 		sendCMArup()
 	}
 	client.Area().RemoveChar(client.CharID())
-	if a.IsTaken(client.CharID()) {
+	client.Area().Unassign(client.Uid())
+	if a.IsTaken(client.CharID()) || !client.CanUseChar(a, client.CharID()) {
+		client.SetCharID(-1)
+	}
+	oldHub, newHub := client.Hub(), getHub(a)
+	oldMusic := oldArea.MusicList()
+	if newHub != oldHub {
+		client.SendPacket("FA", newHub.AreaNames()...)
+	}
+	if !client.JoinArea(a) {
+		// The area was removed while the client was leaving the old one.
+		if newHub != oldHub {
+			client.SendPacket("FA", oldHub.AreaNames()...)
+		}
+		a, newHub = oldHub.Lobby(), oldHub
 		client.SetCharID(-1)
+		client.JoinArea(a)
+		client.SendServerMessage("That area no longer exists.")
+	}
+	if !sliceutil.EqualStrings(oldMusic, a.MusicList()) {
+		client.SendPacket("FM", a.MusicList()...)
+	}
+	if newHub != oldHub {
+		sendHubArups(client)
+		client.SendServerMessage(fmt.Sprintf("Entered hub %v.", newHub.Name()))
+	}
+	if removeOld {
+		removeTempArea(oldArea)
 	}
-	client.JoinArea(a)
 	if client.CharID() == -1 {
 		client.SendPacket("DONE")
 	} else {
-		writeToArea(a, "CharsCheck", a.Taken()...)
+		writeCharsCheck(a)
 	}
+	client.sendRecentIC()
 	addToBuffer(client, "AREA", "Joined area.", false)
-	return true
+	return nil
 }
 
 // HasCMPermission returns whether the client has CM permissions in it's area.
 func (client *Client) HasCMPermission() bool {
-	if client.Area().HasCM(client.Uid()) || permissions.HasPermission(client.Perms(), permissions.PermissionField["CM"]) {
+	if client.Area().HasCM(client.Uid()) || client.HasPermission(permissions.PermissionField["CM"]) {
 		return true
 	} else {
 		return false
@@ -512,7 +659,7 @@ func (client *Client) CanSpeakIC() bool {
 	case client.CharID() == -1:
 		return false
 	case client.Area().Lock() == area.LockSpectatable && !sliceutil.ContainsInt(client.area.Invited(), client.Uid()) &&
-		!permissions.HasPermission(client.Perms(), permissions.PermissionField["BYPASS_LOCK"]):
+		!client.HasPermission(permissions.PermissionField["BYPASS_LOCK"]):
 		return false
 	case client.Muted() == ICMuted || client.Muted() == ICOOCMuted:
 		return client.CheckUnmute()
@@ -536,7 +683,7 @@ func (client *Client) CanChangeMusic() bool {
 	case client.Area().LockMusic() && !client.HasCMPermission():
 		return false
 	case client.Area().Lock() == area.LockSpectatable && !sliceutil.ContainsInt(client.area.Invited(), client.Uid()) &&
-		!permissions.HasPermission(client.Perms(), permissions.PermissionField["BYPASS_LOCK"]):
+		!client.HasPermission(permissions.PermissionField["BYPASS_LOCK"]):
 		return false
 	case client.Muted() == MusicMuted || client.Muted() == ICMuted || client.Muted() == ICOOCMuted:
 		return client.CheckUnmute()
@@ -550,7 +697,13 @@ func (client *Client) CanJud() bool {
 	case client.CharID() == -1:
 		return false
 	case client.Area().Lock() == area.LockSpectatable && !sliceutil.ContainsInt(client.area.Invited(), client.Uid()) &&
-		!permissions.HasPermission(client.Perms(), permissions.PermissionField["BYPASS_LOCK"]):
+		!client.HasPermission(permissions.PermissionField["BYPASS_LOCK"]):
+		return false
+	// Until someone is assigned to the judge position, CMs can still use judge actions.
+	case client.Area().RestrictPositions() && (client.Pos() != "jud" || !client.CanUsePos("jud")) &&
+		(len(client.Area().PosHolders("jud")) > 0 || !client.HasCMPermission()):
+		return false
+	case client.Area().JudgeLocked():
 		return false
 	case client.Muted() == JudMuted || client.Muted() == ICMuted || client.Muted() == ICOOCMuted:
 		return client.CheckUnmute()
@@ -558,6 +711,15 @@ func (client *Client) CanJud() bool {
 	return true
 }
 
+// CanUsePos returns whether the client can use the given position in it's area.
+func (client *Client) CanUsePos(pos string) bool {
+	if !client.Area().RestrictPositions() {
+		return true
+	}
+	holders := client.Area().PosHolders(pos)
+	return len(holders) == 0 || sliceutil.ContainsInt(holders, client.Uid())
+}
+
 // CheckUnmute checks the client's mute duration, unmuting them if nessecary, and returning whether the client is still muted.
 func (client *Client) CheckUnmute() bool {
 	if time.Now().UTC().After(client.UnmuteTime()) && !client.UnmuteTime().IsZero() {
@@ -583,7 +745,7 @@ func (client *Client) CanAlterEvidence() bool {
 	}
 	switch client.Area().EvidenceMode() {
 	case area.EviMods:
-		if !permissions.HasPermission(client.Perms(), permissions.PermissionField["MOD_EVI"]) {
+		if !client.HasPermission(permissions.PermissionField["MOD_EVI"]) {
 			return false
 		}
 	case area.EviCMs:
@@ -596,12 +758,45 @@ func (client *Client) CanAlterEvidence() bool {
 
 // ChangeCharacter changes the client's character to the given character.
 func (client *Client) ChangeCharacter(id int) {
+	if !client.CanUseChar(client.Area(), id) {
+		client.SendServerMessage("You cannot use that character in this area.")
+		return
+	}
 	if client.Area().SwitchChar(client.CharID(), id) {
 		client.SetCharID(id)
 		client.SetShowname(client.CurrentCharacter())
 		client.SendPacket("PV", "0", "CID", strconv.Itoa(id))
-		writeToArea(client.Area(), "CharsCheck", client.Area().Taken()...)
+		writeCharsCheck(client.Area())
+	}
+}
+
+// CanUseChar returns whether the client can use the given character in an area.
+func (client *Client) CanUseChar(a *area.Area, id int) bool {
+	if id == -1 {
+		return true
+	} else if id < 0 || id >= len(characters) {
+		return false
+	}
+	name := characters[id]
+	if !a.CharAllowed(name) || (a.ModOnlyChar(name) && !client.Authenticated()) {
+		return false
+	}
+	if uid, ok := a.ReservedBy(id); ok && uid != client.Uid() {
+		return false
+	}
+	return true
+}
+
+// sendCharsCheck sends the client the characters it cannot use in it's area.
+func (client *Client) sendCharsCheck() {
+	a := client.Area()
+	taken := a.Taken()
+	for id := range taken {
+		if !client.CanUseChar(a, id) {
+			taken[id] = "-1"
+		}
 	}
+	client.SendPacket("CharsCheck", taken...)
 }
 
 // Muted returns the client's mute state.
@@ -20,9 +20,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"math/rand"
 	"net/url"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -30,9 +28,11 @@ import (
 
 	"github.com/MangosArentLiterature/Athena/internal/area"
 	"github.com/MangosArentLiterature/Athena/internal/db"
+	"github.com/MangosArentLiterature/Athena/internal/dice"
 	"github.com/MangosArentLiterature/Athena/internal/logger"
 	"github.com/MangosArentLiterature/Athena/internal/permissions"
 	"github.com/MangosArentLiterature/Athena/internal/sliceutil"
+	"github.com/MangosArentLiterature/Athena/internal/webhook"
 	"github.com/xhit/go-str2duration/v2"
 )
 
@@ -66,22 +66,28 @@ var commands = map[string]cmdMapValue{
 	"mkusr":   {3, "Usage: /mkusr <username> <password> <role>", "Creates a new moderator user.", permissions.PermissionField["ADMIN"], cmdMakeUser},
 	"rmusr":   {1, "Usage: /rmusr <username>", "Removes a moderator user.", permissions.PermissionField["ADMIN"], cmdRemoveUser},
 	"setrole": {2, "Usage: /setrole <username> <role>", "Changes a moderator user's role.", permissions.PermissionField["ADMIN"], cmdChangeRole},
+	"grant":   {3, "Usage: /grant <username> <perm1>,<perm2>... <area1>,<area2>...\nAreas may be given by name, by ID from /hub, or as ranges of IDs, such as 3-6.", "Grants a moderator user permissions within area(s).", permissions.PermissionField["ADMIN"], cmdGrant},
+	"revoke":  {3, "Usage: /revoke <username> <perm1>,<perm2>... <area1>,<area2>...\nAreas may be given by name, by ID from /hub, or as ranges of IDs, such as 3-6.", "Revokes a moderator user's permissions within area(s).", permissions.PermissionField["ADMIN"], cmdRevoke},
+	"grants":  {1, "Usage: /grants <username>", "Shows a moderator user's area permissions.", permissions.PermissionField["ADMIN"], cmdGrants},
 
 	//general commands
-	"about":   {0, "Usage: /about", "Prints Athena version information.", permissions.PermissionField["NONE"], cmdAbout},
-	"move":    {1, "Usage: /move [-u <uid1,<uid2>...] <area>\n-u: Uid(s).", "Moves to an area.", permissions.PermissionField["NONE"], cmdMove},
-	"pm":      {2, "Usage: /pm <uid1>,<uid2>... <message>", "Sends a private message.", permissions.PermissionField["NONE"], cmdPM},
-	"global":  {1, "Usage: /global <message>", "Sends a global message.", permissions.PermissionField["NONE"], cmdGlobal},
-	"roll":    {1, "Usage: /roll [-p] <dice>d<sides>\n-p: Private", "Rolls dice.", permissions.PermissionField["NONE"], cmdRoll},
-	"motd":    {0, "Usage /motd", "Sends the server's message of the day.", permissions.PermissionField["NONE"], cmdMotd},
-	"players": {0, "Usage: /players [-a]\n-a: All.", "Shows players in the current or all areas.", permissions.PermissionField["NONE"], cmdPlayers},
+	"about":    {0, "Usage: /about", "Prints Athena version information.", permissions.PermissionField["NONE"], cmdAbout},
+	"move":     {1, "Usage: /move [-u <uid1,<uid2>...] <area>\n-u: Uid(s).\nThe area may be given by name or by it's ID from /hub.", "Moves to an area.", permissions.PermissionField["NONE"], cmdMove},
+	"pm":       {2, "Usage: /pm <uid1>,<uid2>... <message>", "Sends a private message.", permissions.PermissionField["NONE"], cmdPM},
+	"global":   {1, "Usage: /global <message>", "Sends a global message.", permissions.PermissionField["NONE"], cmdGlobal},
+	"roll":     {1, "Usage: /roll [-p] [-to cm|uid] <dice> [label]\n-p: Private.\n-to: Also sends a private roll to the area's CMs or the given UID.\ndice: An expression such as 2d6+3, 4d6kh3, 2d20kl1, d% or 3d6!.", "Rolls dice.", permissions.PermissionField["NONE"], cmdRoll},
+	"motd":     {0, "Usage /motd", "Sends the server's message of the day.", permissions.PermissionField["NONE"], cmdMotd},
+	"players":  {0, "Usage: /players [-a]\n-a: All.", "Shows players in the current or all areas.", permissions.PermissionField["NONE"], cmdPlayers},
+	"hub":      {0, "Usage: /hub [hub]", "Lists hubs or moves to a hub.", permissions.PermissionField["NONE"], cmdHub},
+	"msstatus": {0, "Usage: /msstatus", "Shows the result of the last master server advertisement.", permissions.PermissionField["NONE"], cmdMSStatus},
 
 	//area commands
 	"bg":           {1, "Usage: /bg <background>", "Sets background.", permissions.PermissionField["CM"], cmdBg},
 	"status":       {1, "Usage: /status <status>", "Sets status.", permissions.PermissionField["CM"], cmdStatus},
 	"cm":           {0, "Usage: /cm [uid1],[uid2]...", "Adds CM(s).", permissions.PermissionField["NONE"], cmdCM},
 	"uncm":         {0, "Usage: /uncm [uid1],[uid2]...", "Removes CM(s).", permissions.PermissionField["CM"], cmdUnCM},
-	"lock":         {0, "Usage: /lock [-s]\n-s: Spectatable.", "Locks the area or sets it to spectatable.", permissions.PermissionField["CM"], cmdLock},
+	"lock":         {0, "Usage: /lock [-s | -p <password>]\n-s: Spectatable.\n-p: Password.", "Locks the area or sets it to spectatable.", permissions.PermissionField["CM"], cmdLock},
+	"join":         {2, "Usage: /join <area> <password>\nThe area may be given by it's ID from /hub, or by name if it has no spaces.", "Joins a password protected area.", permissions.PermissionField["NONE"], cmdJoin},
 	"unlock":       {0, "Usage: /unlock", "Unlocks the area.", permissions.PermissionField["CM"], cmdUnlock},
 	"invite":       {1, "Usage: /invite <uid1>,<uid2>...", "Invites user(s).", permissions.PermissionField["CM"], cmdInvite},
 	"uninvite":     {1, "Usage: /uninvite <uid1>,<uid2>...", "Uninvites user(s).", permissions.PermissionField["CM"], cmdUninvite},
@@ -94,16 +100,33 @@ var commands = map[string]cmdMapValue{
 	"allowcms":     {1, "Usage: /allowcms <true|false>", "Toggles allowing CMs.", permissions.PermissionField["MODIFY_AREA"], cmdAllowCMs},
 	"lockbg":       {1, "Usage: /lockbg <true|false>", "Toggles locking the BG.", permissions.PermissionField["MODIFY_AREA"], cmdLockBG},
 	"lockmusic":    {1, "Usage: /lockmusic <true|false>", "Toggles making music CM only.", permissions.PermissionField["CM"], cmdLockMusic},
+	"case":         {1, "Usage: /case start <title> | end [verdict]", "Starts or ends a case session.", permissions.PermissionField["CM"], cmdCase},
+	"cases":        {0, "Usage: /cases [history]\nhistory: Shows recently finished cases.", "Shows cases looking for players.", permissions.PermissionField["NONE"], cmdCases},
+	"resethp":      {0, "Usage: /resethp", "Resets the penalty bars and unlocks judge controls.", permissions.PermissionField["CM"], cmdResetHP},
+	"assign":       {2, "Usage: /assign <uid1>,<uid2>... <pos|none>", "Assigns user(s) to a position.", permissions.PermissionField["CM"], cmdAssign},
+	"positions":    {0, "Usage: /positions", "Shows the positions of players in the area.", permissions.PermissionField["NONE"], cmdPositions},
+	"reserve":      {1, "Usage: /reserve [-u uid] <character>\n-u: Uid to reserve the character for. If omitted, the reservation is removed.", "Reserves a character for a user.", permissions.PermissionField["CM"], cmdReserve},
+	"jury":         {0, "Usage: /jury [start [-a] [-t duration] <question> [| option1 | option2...]|invite <uid1>,<uid2>...|uninvite <uid1>,<uid2>...|close]\n-a: Anonymous voting.\n-t: How long the vote stays open.", "Shows or manages the area's jury vote.", permissions.PermissionField["NONE"], cmdJury},
+	"vote":         {1, "Usage: /vote <option>", "Votes in the area's jury vote.", permissions.PermissionField["NONE"], cmdVote},
+	"poll":         {0, "Usage: /poll [-g] [create <question> | option1 | option2...|vote <option>|results|close]\n-g: The global poll.", "Shows, votes in, or manages a poll.", permissions.PermissionField["NONE"], cmdPoll},
+	"game":         {0, "Usage: /game [status|roles|join|leave|act <uid>|vote <uid>|create|start|end]", "Plays werewolf in the area.", permissions.PermissionField["NONE"], cmdGame},
+	"turns":        {0, "Usage: /turns [show|add <uid1>,<uid2>...|remove <uid1>,<uid2>...|next|strict <on|off>|timeout <duration>|clear]", "Shows or manages the area's turn order.", permissions.PermissionField["NONE"], cmdTurns},
+	"deck":         {0, "Usage: /deck [show|draw [-p] [count]|shuffle|reset]\n-p: Private.", "Draws from or manages the area's deck of cards.", permissions.PermissionField["NONE"], cmdDeck},
+	"table":        {0, "Usage: /table [-p] [name]\n-p: Private.", "Rolls on a random table, or lists tables.", permissions.PermissionField["NONE"], cmdTable},
 	"charselect":   {0, "Usage: /charselect [uid1],[uid2]...", "Moves back to character select.", permissions.PermissionField["NONE"], cmdCharSelect},
 	"areainfo":     {0, "Usage: /areainfo", "Shows area information.", permissions.PermissionField["NONE"], cmdAreaInfo},
 	"doc":          {0, "Usage: /doc [-c] [doc]\n-c: Clear.", "Gets or sets the doc.", permissions.PermissionField["NONE"], cmdDoc},
-	"play":         {1, "Usage: /play <song>", "Plays a song.", permissions.PermissionField["CM"], cmdPlay},
+	"play":         {1, "Usage: /play [-c channel] [-n] [-fi] [-fo] <song>\n-c: Channel.\n-n: No looping.\n-fi: Fade in.\n-fo: Fade out.", "Plays a song.", permissions.PermissionField["CM"], cmdPlay},
+	"jukebox":      {0, "Usage: /jukebox [on|off|shuffle|list|skip|clear]", "Shows or manages the area's jukebox.", permissions.PermissionField["NONE"], cmdJukebox},
+	"ambience":     {1, "Usage: /ambience <song|stop>", "Sets the area's ambience.", permissions.PermissionField["CM"], cmdAmbience},
+	"makearea":     {1, "Usage: /makearea <name>", "Creates a temporary area.", permissions.PermissionField["NONE"], cmdMakeArea},
+	"timer":        {1, "Usage: /timer [id] start|pause|set <duration> [message]|stop|hide\nid: 0 for the global timer, or 1-4 for area timers. Defaults to 1.", "Controls a countdown timer.", permissions.PermissionField["CM"], cmdTimer},
 	"testimony":    {0, "Usage /testimony <record|stop|play|update|insert|delete>", "Modifies or prints recorded testimony.", permissions.PermissionField["NONE"], cmdTestimony},
 
 	//mod commands
 	"login":   {2, "Usage: /login <username> <password>", "Logs in as moderator.", permissions.PermissionField["NONE"], cmdLogin},
 	"logout":  {0, "Usage: /logout", "Logs out as moderator.", permissions.PermissionField["NONE"], cmdLogout},
-	"kick":    {3, "Usage: /kick -u <uid1>,<uid2>... | -i <ipid1>,<ipid2>... <reason>\n-u: Uid(s).\n-i: Ipid(s).", "Kicks user(s) from the server.", permissions.PermissionField["KICK"], cmdKick},
+	"kick":    {3, "Usage: /kick -u <uid1>,<uid2>... | -i <ipid1>,<ipid2>... <reason>\n-u: Uid(s).\n-i: Ipid(s).", "Kicks user(s) from the server, or to the lobby if you can only kick in this area.", permissions.PermissionField["KICK"], cmdKick},
 	"ban":     {3, "Usage: /ban -u <uid1>,<uid2>... | -i <ipid1>,<ipid2>... [-d duration] <reason>\n-u: Uid(s).\n-i: Ipid(s).\n-d: Duration", "Bans user(s) from the server.", permissions.PermissionField["BAN"], cmdBan},
 	"mod":     {1, "Usage: /mod [-g] <message>\n-g: Global.", "Sends a message speaking officially as a moderator.", permissions.PermissionField["MOD_SPEAK"], cmdMod},
 	"getban":  {0, "Usage: /getban [-b banid | -i ipid]\n-b: BanID.\n-i: IPID.", "Searches bans or gets the most recent bans.", permissions.PermissionField["BAN_INFO"], cmdGetBan},
@@ -113,7 +136,7 @@ var commands = map[string]cmdMapValue{
 	"mute":    {1, "Usage: /mute [-ic][-ooc][-m][-j][-d duration][-r reason] <uid1>,<uid2>...\n-ic: IC.\n-ooc: OOC.\n-m: Music.\n-j: Judge.\n-d: Duration.\n -r: Reason.", "Mutes users(s) from IC/OOC/Music/Judge.", permissions.PermissionField["MUTE"], cmdMute},
 	"unmute":  {1, "Usage: /unmute <uid1>,<uid2>...", "Unmutes user(s).", permissions.PermissionField["MUTE"], cmdUnmute},
 	"parrot":  {1, "Usage: /parrot [-d duration][-r reason] <uid1>,<uid2>...\n-d: Duration.\n-r: Reason.", "Parrots user(s).", permissions.PermissionField["MUTE"], cmdParrot},
-	"log":     {1, "Usage: /log <area>", "Gets an area's log buffer.", permissions.PermissionField["LOG"], cmdLog},
+	"log":     {1, "Usage: /log <area>\nThe area may be given by name or by it's ID from /hub.", "Gets an area's log buffer.", permissions.PermissionField["LOG"], cmdLog},
 }
 
 // ParseCommand calls the appropriate function for a given command.
@@ -121,7 +144,7 @@ func ParseCommand(client *Client, command string, args []string) {
 	if command == "help" {
 		var s []string
 		for name, attr := range commands {
-			if permissions.HasPermission(client.Perms(), attr.Permission) || (attr.Permission == permissions.PermissionField["CM"] && client.Area().HasCM(client.Uid())) {
+			if client.HasPermission(attr.Permission) || (attr.Permission == permissions.PermissionField["CM"] && client.Area().HasCM(client.Uid())) {
 				s = append(s, fmt.Sprintf("- /%v: %v", name, attr.Desc))
 			}
 		}
@@ -134,7 +157,7 @@ func ParseCommand(client *Client, command string, args []string) {
 	if cmd.Func == nil {
 		client.SendServerMessage("Invalid command.")
 		return
-	} else if permissions.HasPermission(client.Perms(), cmd.Permission) || (cmd.Permission == permissions.PermissionField["CM"] && client.Area().HasCM(client.Uid())) {
+	} else if client.HasPermission(cmd.Permission) || (cmd.Permission == permissions.PermissionField["CM"] && client.Area().HasCM(client.Uid())) {
 		if sliceutil.ContainsString(args, "-h") {
 			client.SendServerMessage(cmd.Usage)
 			return
@@ -149,6 +172,28 @@ func ParseCommand(client *Client, command string, args []string) {
 	}
 }
 
+// applyCommandOverrides changes the permissions required by commands according to the server's role config.
+// Commands overridden with "DISABLED" are removed entirely.
+func applyCommandOverrides(overrides map[string]string) error {
+	for name, perm := range overrides {
+		cmd, ok := commands[name]
+		if !ok {
+			return fmt.Errorf("cannot override unknown command %q", name)
+		}
+		if strings.ToUpper(perm) == "DISABLED" {
+			delete(commands, name)
+			continue
+		}
+		p, err := permissions.ParsePermissions([]string{perm})
+		if err != nil {
+			return fmt.Errorf("command %q: %v", name, err)
+		}
+		cmd.Permission = p
+		commands[name] = cmd
+	}
+	return nil
+}
+
 // Handles /login
 func cmdLogin(client *Client, args []string, _ string) {
 	if client.Authenticated() {
@@ -160,15 +205,27 @@ func cmdLogin(client *Client, args []string, _ string) {
 	if auth {
 		client.SetAuthenticated(true)
 		client.SetPerms(perms)
+		client.SetAreaPerms(getAreaPerms(args[0]))
 		client.SetModName(args[0])
 		client.SendServerMessage("Logged in as moderator.")
 		client.SendPacket("AUTH", "1")
+		client.sendCharsCheck()
 		client.SendServerMessage(fmt.Sprintf("Welcome, %v.", args[0]))
 		addToBuffer(client, "AUTH", fmt.Sprintf("Logged in as %v.", args[0]), true)
+		webhook.Post(webhook.Event{
+			Type:   webhook.EventLogin,
+			Title:  fmt.Sprintf("%v logged in as moderator.", args[0]),
+			Fields: []webhook.Field{{Name: "IPID", Value: client.Ipid()}},
+		})
 		return
 	}
 	client.SendPacket("AUTH", "0")
 	addToBuffer(client, "AUTH", fmt.Sprintf("Failed login as %v.", args[0]), true)
+	webhook.Post(webhook.Event{
+		Type:   webhook.EventLogin,
+		Title:  fmt.Sprintf("Failed login attempt as %v.", args[0]),
+		Fields: []webhook.Field{{Name: "IPID", Value: client.Ipid()}},
+	})
 }
 
 // Handles /logout
@@ -253,6 +310,116 @@ func cmdChangeRole(client *Client, args []string, _ string) {
 	addToBuffer(client, "CMD", fmt.Sprintf("Updated role of %v to %v.", args[0], args[1]), true)
 }
 
+// Handles /grant
+func cmdGrant(client *Client, args []string, _ string) {
+	changeAreaPerms(client, args, true)
+}
+
+// Handles /revoke
+func cmdRevoke(client *Client, args []string, _ string) {
+	changeAreaPerms(client, args, false)
+}
+
+// changeAreaPerms grants or revokes a moderator user's permissions within a list of areas.
+func changeAreaPerms(client *Client, args []string, grant bool) {
+	if !db.UserExists(args[0]) {
+		client.SendServerMessage("User does not exist.")
+		return
+	}
+	perms, err := permissions.ParsePermissions(strings.Split(args[1], ","))
+	if err != nil || perms == 0 {
+		client.SendServerMessage("Invalid permission.")
+		return
+	} else if perms&^permissions.AreaScoped != 0 {
+		client.SendServerMessage("Only area permissions can be granted or revoked: " + strings.Join(permissions.PermissionNames(permissions.AreaScoped), ", ") + ".")
+		return
+	}
+	list, err := getAreaList(client, args[2])
+	if err != nil {
+		client.SendServerMessage("Invalid area.")
+		return
+	}
+	var refs []db.AreaRef
+	for _, a := range list {
+		if isTempArea(a) {
+			client.SendServerMessage(fmt.Sprintf("Cannot change permissions in temporary area %v.", a.Name()))
+			return
+		}
+		refs = append(refs, areaRef(a))
+	}
+	current, err := db.GetAreaPermissions(args[0])
+	if err != nil {
+		client.SendServerMessage("Failed to change permissions.")
+		logger.LogError(err.Error())
+		return
+	}
+	for _, ref := range refs {
+		p := current[ref]
+		if grant {
+			p |= perms
+		} else {
+			p &^= perms
+		}
+		err = db.SetAreaPermissions(args[0], ref, p)
+		if err != nil {
+			client.SendServerMessage("Failed to change permissions.")
+			logger.LogError(err.Error())
+			return
+		}
+	}
+
+	areaPerms := getAreaPerms(args[0])
+	for c := range clients.GetAllClients() {
+		if c.Authenticated() && c.ModName() == args[0] {
+			c.SetAreaPerms(areaPerms)
+		}
+	}
+	if grant {
+		client.SendServerMessage("Permissions granted.")
+		addToBuffer(client, "CMD", fmt.Sprintf("Granted %v to %v in areas %v.", args[1], args[0], args[2]), true)
+	} else {
+		client.SendServerMessage("Permissions revoked.")
+		addToBuffer(client, "CMD", fmt.Sprintf("Revoked %v from %v in areas %v.", args[1], args[0], args[2]), true)
+	}
+}
+
+// Handles /grants
+func cmdGrants(client *Client, args []string, _ string) {
+	if !db.UserExists(args[0]) {
+		client.SendServerMessage("User does not exist.")
+		return
+	}
+	perms, err := db.GetAreaPermissions(args[0])
+	if err != nil {
+		logger.LogErrorf("while getting area permissions: %v", err)
+		client.SendServerMessage("An unexpected error occured.")
+		return
+	}
+	if len(perms) == 0 {
+		client.SendServerMessage(fmt.Sprintf("%v has no area permissions.", args[0]))
+		return
+	}
+	refs := make([]db.AreaRef, 0, len(perms))
+	for ref := range perms {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Hub != refs[j].Hub {
+			return refs[i].Hub < refs[j].Hub
+		}
+		return refs[i].Area < refs[j].Area
+	})
+	s := fmt.Sprintf("Area permissions for %v:\n----------", args[0])
+	for _, ref := range refs {
+		name := fmt.Sprintf("%v (hub %v)", ref.Area, ref.Hub)
+		if getAreaByRef(ref) == nil {
+			name += " (no longer exists)"
+		}
+		s += fmt.Sprintf("\n%v: %v", name, strings.Join(permissions.PermissionNames(perms[ref]), ", "))
+	}
+	client.SendServerMessage(s)
+}
+
 // Handles /kick
 func cmdKick(client *Client, args []string, usage string) {
 	flags := flag.NewFlagSet("", 0)
@@ -281,16 +448,43 @@ func cmdKick(client *Client, args []string, usage string) {
 	var count int
 	var report string
 	reason := strings.Join(flags.Args(), " ")
+	// Moderators who can only kick within areas send the user to the lobby instead of disconnecting them.
+	global := client.HasGlobalPermission(permissions.PermissionField["KICK"])
 	for _, c := range toKick {
+		if !client.CanModerate(c, permissions.PermissionField["KICK"]) {
+			continue
+		}
+		if !global {
+			if c.Area() == c.Hub().Lobby() {
+				client.SendServerMessage(fmt.Sprintf("Failed to kick %v: Cannot kick a user from the lobby.", c.Uid()))
+				continue
+			} else if err := c.ChangeArea(c.Hub().Lobby()); err != nil {
+				client.SendServerMessage(fmt.Sprintf("Failed to kick %v: %v.", c.Uid(), err))
+				continue
+			}
+			c.SendServerMessage(fmt.Sprintf("You were kicked from the area for reason: %v", reason))
+		} else {
+			c.SendPacket("KK", reason)
+			c.conn.Close()
+		}
 		report += c.Ipid() + ", "
-		c.SendPacket("KK", reason)
-		c.conn.Close()
 		count++
 	}
 	report = strings.TrimSuffix(report, ", ")
 	client.SendServerMessage(fmt.Sprintf("Kicked %v clients.", count))
 	sendPlayerArup()
+	if !global {
+		addToBuffer(client, "CMD", fmt.Sprintf("Kicked %v from area for reason: %v.", report, reason), true)
+		return
+	}
 	addToBuffer(client, "CMD", fmt.Sprintf("Kicked %v from server for reason: %v.", report, reason), true)
+	if count > 0 {
+		webhook.Post(webhook.Event{
+			Type:   webhook.EventKick,
+			Title:  fmt.Sprintf("%v kicked %v clients.", client.ModName(), count),
+			Fields: []webhook.Field{{Name: "IPIDs", Value: report}, {Name: "Reason", Value: reason}},
+		})
+	}
 }
 
 // Handles /ban
@@ -335,6 +529,9 @@ func cmdBan(client *Client, args []string, usage string) {
 	var count int
 	var report string
 	for _, c := range toBan {
+		if !client.CanModerate(c, permissions.PermissionField["BAN"]) {
+			continue
+		}
 		id, err := db.AddBan(c.Ipid(), c.Hdid(), banTime, until, reason, client.ModName())
 		if err != nil {
 			continue
@@ -356,12 +553,19 @@ func cmdBan(client *Client, args []string, usage string) {
 	client.SendServerMessage(fmt.Sprintf("Banned %v clients.", count))
 	sendPlayerArup()
 	addToBuffer(client, "CMD", fmt.Sprintf("Banned %v from server for %v: %v.", report, *duration, reason), true)
+	if count > 0 {
+		webhook.Post(webhook.Event{
+			Type:   webhook.EventBan,
+			Title:  fmt.Sprintf("%v banned %v clients.", client.ModName(), count),
+			Fields: []webhook.Field{{Name: "IPIDs", Value: report}, {Name: "Duration", Value: *duration}, {Name: "Reason", Value: reason}},
+		})
+	}
 }
 
 // Handles /kickarea
 func cmdAreaKick(client *Client, args []string, _ string) {
-	if client.Area() == areas[0] {
-		client.SendServerMessage("Failed to kick: Cannot kick a user from area 0.")
+	if client.Area() == client.Hub().Lobby() {
+		client.SendServerMessage("Failed to kick: Cannot kick a user from the lobby.")
 		return
 	}
 	toKick := getUidList(strings.Split(args[0], ","))
@@ -369,14 +573,17 @@ func cmdAreaKick(client *Client, args []string, _ string) {
 	var count int
 	var report string
 	for _, c := range toKick {
-		if c.Area() != client.Area() || permissions.HasPermission(c.Perms(), permissions.PermissionField["BYPASS_LOCK"]) {
+		if c.Area() != client.Area() || c.HasPermission(permissions.PermissionField["BYPASS_LOCK"]) {
 			continue
 		}
 		if c == client {
 			client.SendServerMessage("You can't kick yourself from the area.")
 			continue
 		}
-		c.ChangeArea(areas[0])
+		if err := c.ChangeArea(c.Hub().Lobby()); err != nil {
+			client.SendServerMessage(fmt.Sprintf("Failed to kick %v: %v.", c.Uid(), err))
+			continue
+		}
 		c.SendServerMessage("You were kicked from the area!")
 		count++
 		report += fmt.Sprintf("%v, ", c.Uid())
@@ -388,14 +595,14 @@ func cmdAreaKick(client *Client, args []string, _ string) {
 
 // Handles /bg
 func cmdBg(client *Client, args []string, _ string) {
-	if client.Area().LockBG() && !permissions.HasPermission(client.Perms(), permissions.PermissionField["MODIFY_AREA"]) {
+	if client.Area().LockBG() && !client.HasPermission(permissions.PermissionField["MODIFY_AREA"]) {
 		client.SendServerMessage("You do not have permission to change the background in this area.")
 		return
 	}
 
 	arg := strings.Join(args, " ")
 
-	if client.Area().ForceBGList() && !sliceutil.ContainsString(backgrounds, arg) {
+	if client.Area().ForceBGList() && !sliceutil.ContainsString(client.Area().Backgrounds(), arg) {
 		client.SendServerMessage("Invalid background.")
 		return
 	}
@@ -425,7 +632,7 @@ func cmdCM(client *Client, args []string, _ string) {
 		if client.Area().HasCM(client.Uid()) {
 			client.SendServerMessage("You are already a CM in this area.")
 			return
-		} else if len(client.Area().CMs()) > 0 && !permissions.HasPermission(client.Perms(), permissions.PermissionField["CM"]) {
+		} else if len(client.Area().CMs()) > 0 && !client.HasPermission(permissions.PermissionField["CM"]) {
 			client.SendServerMessage("This area already has a CM.")
 			return
 		}
@@ -507,21 +714,47 @@ func cmdStatus(client *Client, args []string, _ string) {
 	}
 	sendAreaServerMessage(client.Area(), fmt.Sprintf("%v set the status to %v.", client.OOCName(), args[0]))
 	sendStatusArup()
+	updateAdvert()
 	addToBuffer(client, "CMD", fmt.Sprintf("Set the status to %v.", args[0]), false)
 }
 
 // Handles /lock
 func cmdLock(client *Client, args []string, _ string) {
-	if sliceutil.ContainsString(args, "-s") { // Set area to spectatable.
+	flags := flag.NewFlagSet("", 0)
+	flags.SetOutput(io.Discard)
+	spectatable := flags.Bool("s", false, "")
+	password := flags.String("p", "", "")
+	flags.Parse(args)
+	if *password != "" {
+		// Passwords may contain spaces, matching how /join reads them.
+		*password = strings.Join(append([]string{*password}, flags.Args()...), " ")
+	}
+
+	if *spectatable { // Set area to spectatable.
 		client.Area().SetLock(area.LockSpectatable)
+		client.Area().SetPassword("")
 		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v set the area to spectatable.", client.OOCName()))
 		addToBuffer(client, "CMD", "Set the area to spectatable.", false)
+	} else if *password != "" { // Password lock.
+		if client.Area() == client.Hub().Lobby() {
+			client.SendServerMessage("You cannot lock the lobby.")
+			return
+		}
+		err := client.Area().SetPassword(*password)
+		if err != nil {
+			logger.LogErrorf("while setting area password: %v", err)
+			client.SendServerMessage("Failed to set the area's password.")
+			return
+		}
+		client.Area().SetLock(area.LockLocked)
+		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v locked the area with a password.", client.OOCName()))
+		addToBuffer(client, "CMD", "Locked the area with a password.", false)
 	} else { // Normal lock.
 		if client.Area().Lock() == area.LockLocked {
 			client.SendServerMessage("This area is already locked.")
 			return
-		} else if client.Area() == areas[0] {
-			client.SendServerMessage("You cannot lock area 0.")
+		} else if client.Area() == client.Hub().Lobby() {
+			client.SendServerMessage("You cannot lock the lobby.")
 			return
 		}
 		client.Area().SetLock(area.LockLocked)
@@ -543,6 +776,7 @@ func cmdUnlock(client *Client, _ []string, _ string) {
 		return
 	}
 	client.Area().SetLock(area.LockFree)
+	client.Area().SetPassword("")
 	client.Area().ClearInvited()
 	sendLockArup()
 	sendAreaServerMessage(client.Area(), fmt.Sprintf("%v unlocked the area.", client.OOCName()))
@@ -584,9 +818,12 @@ func cmdUninvite(client *Client, args []string, _ string) {
 			continue
 		}
 		if client.Area().RemoveInvited(c.Uid()) {
-			if c.Area() == client.Area() && client.Area().Lock() == area.LockLocked && !permissions.HasPermission(c.Perms(), permissions.PermissionField["BYPASS_LOCK"]) {
-				c.SendServerMessage("You were kicked from the area!")
-				c.ChangeArea(areas[0])
+			if c.Area() == client.Area() && client.Area().Lock() == area.LockLocked && !c.HasPermission(permissions.PermissionField["BYPASS_LOCK"]) {
+				if err := c.ChangeArea(c.Hub().Lobby()); err != nil {
+					client.SendServerMessage(fmt.Sprintf("Failed to kick %v: %v.", c.Uid(), err))
+				} else {
+					c.SendServerMessage("You were kicked from the area!")
+				}
 			}
 			c.SendServerMessage(fmt.Sprintf("You were uninvited from area %v.", client.Area().Name()))
 			count++
@@ -629,7 +866,7 @@ func cmdSetEviMod(client *Client, args []string, _ string) {
 	}
 	switch args[0] {
 	case "mods":
-		if !permissions.HasPermission(client.Perms(), permissions.PermissionField["MOD_EVI"]) {
+		if !client.HasPermission(permissions.PermissionField["MOD_EVI"]) {
 			client.SendServerMessage("You do not have permission for this evidence mode.")
 			return
 		}
@@ -753,6 +990,17 @@ func cmdAllowCMs(client *Client, args []string, _ string) {
 	addToBuffer(client, "CMD", fmt.Sprintf("Set allowing CMs to %v.", args[0]), false)
 }
 
+// Handles /resethp
+func cmdResetHP(client *Client, _ []string, _ string) {
+	client.Area().SetHP(1, 10)
+	client.Area().SetHP(2, 10)
+	client.Area().SetJudgeLocked(false)
+	writeToArea(client.Area(), "HP", "1", "10")
+	writeToArea(client.Area(), "HP", "2", "10")
+	sendAreaServerMessage(client.Area(), fmt.Sprintf("%v reset the penalty bars.", client.OOCName()))
+	addToBuffer(client, "CMD", "Reset the penalty bars.", false)
+}
+
 // Handles /move
 func cmdMove(client *Client, args []string, usage string) {
 	flags := flag.NewFlagSet("", 0)
@@ -765,15 +1013,17 @@ func cmdMove(client *Client, args []string, usage string) {
 		client.SendServerMessage("Not enough arguments:\n" + usage)
 		return
 	}
-	areaID, err := strconv.Atoi(flags.Arg(0))
-	if err != nil || areaID < 0 || areaID > len(areas)-1 {
+	wantedArea := getAreaArg(client, strings.Join(flags.Args(), " "))
+	if wantedArea == nil {
 		client.SendServerMessage("Invalid area.")
 		return
+	} else if hub := getHub(wantedArea); hub != client.Hub() {
+		client.SendServerMessage(fmt.Sprintf("That area is in hub %v. Use /hub %v to switch hubs first.", hub.Name(), hub.Name()))
+		return
 	}
-	wantedArea := areas[areaID]
 
 	if len(*uids) > 0 {
-		if !permissions.HasPermission(client.Perms(), permissions.PermissionField["MOVE_USERS"]) {
+		if !client.HasPermission(permissions.PermissionField["MOVE_USERS"]) {
 			client.SendServerMessage("You do not have permission to use that command.")
 			return
 		}
@@ -781,7 +1031,7 @@ func cmdMove(client *Client, args []string, usage string) {
 		var count int
 		var report string
 		for _, c := range toMove {
-			if !c.ChangeArea(wantedArea) {
+			if !client.CanModerate(c, permissions.PermissionField["MOVE_USERS"]) || c.ChangeArea(wantedArea) != nil {
 				continue
 			}
 			c.SendServerMessage(fmt.Sprintf("You were moved to %v.", wantedArea.Name()))
@@ -792,13 +1042,114 @@ func cmdMove(client *Client, args []string, usage string) {
 		client.SendServerMessage(fmt.Sprintf("Moved %v users.", count))
 		addToBuffer(client, "CMD", fmt.Sprintf("Moved %v to %v.", report, wantedArea.Name()), false)
 	} else {
-		if !client.ChangeArea(wantedArea) {
-			client.SendServerMessage("You are not invited to that area.")
+		if err := client.ChangeArea(wantedArea); err != nil {
+			client.SendServerMessage(fmt.Sprintf("Failed to move: %v.", err))
+			return
 		}
 		client.SendServerMessage(fmt.Sprintf("Moved to %v.", wantedArea.Name()))
 	}
 }
 
+// Handles /makearea
+func cmdMakeArea(client *Client, args []string, _ string) {
+	if config.MaxTempAreas <= 0 {
+		client.SendServerMessage("Temporary areas are disabled on this server.")
+		return
+	}
+	name := strings.TrimSpace(strings.Join(args, " "))
+	if name == "" || len(name) > 32 || strings.ContainsAny(name, "#%$&") {
+		client.SendServerMessage("Invalid area name.")
+		return
+	}
+	a, err := makeTempArea(client.Hub(), name)
+	if err != nil {
+		client.SendServerMessage(fmt.Sprintf("Failed to create area: %v.", err))
+		return
+	}
+	if err := client.ChangeArea(a); err != nil {
+		removeTempArea(a)
+		client.SendServerMessage(fmt.Sprintf("Failed to move: %v.", err))
+		return
+	}
+	a.AddCM(client.Uid())
+	sendCMArup()
+	client.SendServerMessage(fmt.Sprintf("Created area %v. You are now a CM in this area.", a.Name()))
+	addToBuffer(client, "CMD", fmt.Sprintf("Created temporary area %v.", a.Name()), false)
+}
+
+// Handles /hub
+func cmdHub(client *Client, args []string, _ string) {
+	if len(args) == 0 {
+		out := "\nHubs\n----------\n"
+		for i, h := range hubs {
+			var count int
+			var areaList []string
+			for _, a := range h.Areas() {
+				count += a.PlayerCount()
+				areaList = append(areaList, fmt.Sprintf("[%v] %v", a.ID(), a.Name()))
+			}
+			out += fmt.Sprintf("[%v] %v: %v players online.", i, h.Name(), count)
+			if h == client.Hub() {
+				out += " (current)"
+			}
+			out += fmt.Sprintf("\nAreas: %v\n", strings.Join(areaList, ", "))
+		}
+		client.SendServerMessage(out)
+		return
+	}
+	name := strings.Join(args, " ")
+	hub := getHubByName(name)
+	if hub == nil {
+		id, err := strconv.Atoi(name)
+		if err != nil || id < 0 || id > len(hubs)-1 {
+			client.SendServerMessage("Invalid hub.")
+			return
+		}
+		hub = hubs[id]
+	}
+	if hub == client.Hub() {
+		client.SendServerMessage("You are already in that hub.")
+		return
+	}
+	if err := client.ChangeArea(hub.Lobby()); err != nil {
+		client.SendServerMessage(fmt.Sprintf("Failed to move: %v.", err))
+	}
+}
+
+// Handles /join
+func cmdJoin(client *Client, args []string, _ string) {
+	wantedArea := getAreaArg(client, args[0])
+	if wantedArea == nil {
+		client.SendServerMessage("Invalid area.")
+		return
+	} else if hub := getHub(wantedArea); hub != client.Hub() {
+		client.SendServerMessage(fmt.Sprintf("That area is in hub %v. Use /hub %v to switch hubs first.", hub.Name(), hub.Name()))
+		return
+	} else if wantedArea == client.Area() {
+		client.SendServerMessage("You are already in that area.")
+		return
+	}
+	if wait := joinAttemptWait(client.Ipid()); wait > 0 {
+		client.SendServerMessage(fmt.Sprintf("Too many incorrect passwords. Please wait %v before trying again.", wait.Round(time.Second)))
+		return
+	}
+	if !wantedArea.CheckPassword(strings.Join(args[1:], " ")) {
+		failJoinAttempt(client.Ipid())
+		client.SendServerMessage("Incorrect password.")
+		return
+	}
+	// The invite lets the client past the lock, and is withdrawn if the move fails.
+	invited := wantedArea.AddInvited(client.Uid())
+	if err := client.ChangeArea(wantedArea); err != nil {
+		if invited {
+			wantedArea.RemoveInvited(client.Uid())
+		}
+		client.SendServerMessage(fmt.Sprintf("Failed to move: %v.", err))
+		return
+	}
+	client.SendServerMessage(fmt.Sprintf("Moved to %v.", wantedArea.Name()))
+}
+
 // Handles /charselect
 func cmdCharSelect(client *Client, args []string, _ string) {
 	if len(args) == 0 {
@@ -828,6 +1179,52 @@ func cmdCharSelect(client *Client, args []string, _ string) {
 	}
 }
 
+// Handles /reserve
+func cmdReserve(client *Client, args []string, usage string) {
+	flags := flag.NewFlagSet("", 0)
+	flags.SetOutput(io.Discard)
+	uid := flags.Int("u", -1, "")
+	flags.Parse(args)
+	if len(flags.Args()) < 1 {
+		client.SendServerMessage("Not enough arguments:\n" + usage)
+		return
+	}
+	name := strings.Join(flags.Args(), " ")
+	char := getCharacterID(name)
+	if char == -1 {
+		client.SendServerMessage("Invalid character.")
+		return
+	}
+	if *uid == -1 {
+		if !client.Area().Unreserve(char) {
+			client.SendServerMessage("That character is not reserved.")
+			return
+		}
+		writeCharsCheck(client.Area())
+		client.SendServerMessage(fmt.Sprintf("Removed the reservation for %v.", name))
+		addToBuffer(client, "CMD", fmt.Sprintf("Removed the reservation for %v.", name), false)
+		return
+	}
+	c, err := getClientByUid(*uid)
+	if err != nil || c.Area() != client.Area() {
+		client.SendServerMessage("Invalid uid. Characters can only be reserved for users in this area.")
+		return
+	}
+	client.Area().Reserve(char, c.Uid())
+	for holder := range clients.GetAllClients() {
+		if holder.Area() == client.Area() && holder.CharID() == char && holder != c {
+			holder.ChangeCharacter(-1)
+			holder.SendPacket("DONE")
+			holder.SendServerMessage(fmt.Sprintf("You were moved back to character select, as %v has been reserved for another user.", name))
+			client.SendServerMessage(fmt.Sprintf("Moved %v, who was using %v, back to character select.", holder.Uid(), name))
+		}
+	}
+	writeCharsCheck(client.Area())
+	c.SendServerMessage(fmt.Sprintf("%v has been reserved for you in area %v.", name, client.Area().Name()))
+	client.SendServerMessage(fmt.Sprintf("Reserved %v for %v.", name, c.Uid()))
+	addToBuffer(client, "CMD", fmt.Sprintf("Reserved %v for %v.", name, c.Uid()), false)
+}
+
 // Handles /players
 func cmdPlayers(client *Client, args []string, _ string) {
 	flags := flag.NewFlagSet("", 0)
@@ -849,7 +1246,7 @@ func cmdPlayers(client *Client, args []string, _ string) {
 		return s
 	}
 	if *all {
-		for _, a := range areas {
+		for _, a := range getAreas() {
 			out += fmt.Sprintf("%v:\n%v players online.\n", a.Name(), a.PlayerCount())
 			for c := range clients.GetAllClients() {
 				if c.Area() == a {
@@ -871,9 +1268,12 @@ func cmdPlayers(client *Client, args []string, _ string) {
 
 // Handles /areainfo
 func cmdAreaInfo(client *Client, _ []string, _ string) {
-	out := fmt.Sprintf("\nBG: %v\nEvi mode: %v\nAllow iniswap: %v\nNon-interrupting pres: %v\nCMs allowed: %v\nForce BG list: %v\nBG locked: %v\nMusic locked: %v",
-		client.Area().Background(), client.Area().EvidenceMode().String(), client.Area().IniswapAllowed(), client.Area().NoInterrupt(),
-		client.Area().CMsAllowed(), client.Area().ForceBGList(), client.Area().LockBG(), client.Area().LockMusic())
+	out := fmt.Sprintf("\nID: %v\nBG: %v\nEvi mode: %v\nAllow iniswap: %v\nNon-interrupting pres: %v\nCMs allowed: %v\nForce BG list: %v\nBG locked: %v\nMusic locked: %v\nPassword protected: %v",
+		client.Area().ID(), client.Area().Background(), client.Area().EvidenceMode().String(), client.Area().IniswapAllowed(), client.Area().NoInterrupt(),
+		client.Area().CMsAllowed(), client.Area().ForceBGList(), client.Area().LockBG(), client.Area().LockMusic(), client.Area().HasPassword())
+	if maxPlayers := client.Area().MaxPlayers(); maxPlayers > 0 {
+		out += fmt.Sprintf("\nMax players: %v", maxPlayers)
+	}
 	client.SendServerMessage(out)
 }
 
@@ -896,34 +1296,56 @@ func cmdGlobal(client *Client, args []string, _ string) {
 }
 
 // Handles /roll
-func cmdRoll(client *Client, args []string, _ string) {
+func cmdRoll(client *Client, args []string, usage string) {
 	flags := flag.NewFlagSet("", 0)
 	flags.SetOutput(io.Discard)
 	private := flags.Bool("p", false, "")
+	to := flags.String("to", "", "")
 	flags.Parse(args)
-	b, _ := regexp.MatchString("([[:digit:]])d([[:digit:]])", flags.Arg(0))
-	if !b {
-		client.SendServerMessage("Argument not recognized.")
+	if flags.NArg() < 1 {
+		client.SendServerMessage("Not enough arguments.\n" + usage)
 		return
 	}
-	s := strings.Split(flags.Arg(0), "d")
-	num, _ := strconv.Atoi(s[0])
-	sides, _ := strconv.Atoi(s[1])
-	if num <= 0 || num > config.MaxDice || sides <= 0 || sides > config.MaxSide {
-		client.SendServerMessage("Invalid num/side.")
+	res, err := dice.Roll(flags.Arg(0), config.MaxDice, config.MaxSide)
+	if err != nil {
+		client.SendServerMessage(fmt.Sprintf("Invalid roll: %v.", err))
 		return
 	}
-	var result []string
-	gen := rand.New(rand.NewSource(time.Now().Unix()))
-	for i := 0; i < num; i++ {
-		result = append(result, fmt.Sprint(gen.Intn(sides)+1))
+	label := strings.Join(flags.Args()[1:], " ")
+	if label != "" {
+		label = fmt.Sprintf(" for %v", label)
+	}
+
+	var recipients []*Client
+	switch {
+	case *to == "cm":
+		for _, uid := range client.Area().CMs() {
+			if c, err := getClientByUid(uid); err == nil && c != client {
+				recipients = append(recipients, c)
+			}
+		}
+		if len(recipients) == 0 {
+			client.SendServerMessage("This area has no other CMs.")
+			return
+		}
+	case *to != "":
+		recipients = getUidList([]string{*to})
+		if len(recipients) == 0 {
+			client.SendServerMessage("Could not find that user.")
+			return
+		}
 	}
-	if *private {
-		client.SendServerMessage(fmt.Sprintf("Results: %v.", strings.Join(result, ", ")))
+
+	msg := fmt.Sprintf("%v rolled %v%v: %v", client.OOCName(), flags.Arg(0), label, res)
+	if *private || len(recipients) > 0 {
+		client.SendServerMessage(fmt.Sprintf("You privately rolled %v%v: %v", flags.Arg(0), label, res))
+		for _, c := range recipients {
+			c.SendServerMessage(fmt.Sprintf("[Private] %v", msg))
+		}
 	} else {
-		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v rolled %v. Results: %v.", client.OOCName(), flags.Arg(0), strings.Join(result, ", ")))
+		sendAreaServerMessage(client.Area(), msg)
 	}
-	addToBuffer(client, "CMD", fmt.Sprintf("Rolled %v.", flags.Arg(0)), false)
+	addToBuffer(client, "CMD", fmt.Sprintf("Rolled %v%v: %v", flags.Arg(0), label, res), false)
 }
 
 // Handles /motd
@@ -931,6 +1353,26 @@ func cmdMotd(client *Client, _ []string, _ string) {
 	client.SendServerMessage(config.Motd)
 }
 
+// Handles /msstatus
+func cmdMSStatus(client *Client, _ []string, _ string) {
+	if advertiser == nil {
+		client.SendServerMessage("This server is not advertising on a master server.")
+		return
+	}
+	s := "Master server status:\n----------"
+	for _, st := range advertiser.Status() {
+		switch {
+		case st.Time.IsZero():
+			s += fmt.Sprintf("\n%v: Not yet posted.", st.Addr)
+		case st.Err != nil:
+			s += fmt.Sprintf("\n%v: Failed at %v: %v", st.Addr, st.Time.Format("15:04:05 MST"), st.Err)
+		default:
+			s += fmt.Sprintf("\n%v: OK at %v", st.Addr, st.Time.Format("15:04:05 MST"))
+		}
+	}
+	client.SendServerMessage(s)
+}
+
 // Handles /mod
 func cmdMod(client *Client, args []string, usage string) {
 	flags := flag.NewFlagSet("", 0)
@@ -943,6 +1385,10 @@ func cmdMod(client *Client, args []string, usage string) {
 	}
 	msg := strings.Join(flags.Args(), " ")
 	if *global {
+		if !client.HasGlobalPermission(permissions.PermissionField["MOD_SPEAK"]) {
+			client.SendServerMessage("You do not have permission to send global mod messages.")
+			return
+		}
 		writeToAll("CT", fmt.Sprintf("[MOD] [GLOBAL] %v", client.OOCName()), msg, "1")
 	} else {
 		writeToArea(client.Area(), "CT", fmt.Sprintf("[MOD] %v", client.OOCName()), msg, "1")
@@ -1044,7 +1490,7 @@ func cmdEditBan(client *Client, args []string, _ string) {
 func cmdModChat(client *Client, args []string, _ string) {
 	msg := strings.Join(args, " ")
 	for c := range clients.GetAllClients() {
-		if permissions.HasPermission(c.Perms(), permissions.PermissionField["MOD_CHAT"]) {
+		if c.HasPermission(permissions.PermissionField["MOD_CHAT"]) {
 			c.SendPacket("CT", fmt.Sprintf("[MODCHAT] %v", client.OOCName()), msg, "1")
 		}
 	}
@@ -1092,6 +1538,9 @@ func cmdMute(client *Client, args []string, usage string) {
 	var count int
 	var report string
 	for _, c := range toMute {
+		if !client.CanModerate(c, permissions.PermissionField["MUTE"]) {
+			continue
+		}
 		if c.Muted() == m {
 			continue
 		}
@@ -1108,6 +1557,9 @@ func cmdMute(client *Client, args []string, usage string) {
 	report = strings.TrimSuffix(report, ", ")
 	client.SendServerMessage(fmt.Sprintf("Muted %v clients.", count))
 	addToBuffer(client, "CMD", fmt.Sprintf("Muted %v.", report), false)
+	if count > 0 {
+		postMuteEvent(client, fmt.Sprintf("muted %v clients %v", count, m.String()), report, *duration, *reason)
+	}
 }
 
 // Handles /unmute
@@ -1116,6 +1568,9 @@ func cmdUnmute(client *Client, args []string, _ string) {
 	var count int
 	var report string
 	for _, c := range toUnmute {
+		if !client.CanModerate(c, permissions.PermissionField["MUTE"]) {
+			continue
+		}
 		if c.Muted() == Unmuted {
 			continue
 		}
@@ -1151,6 +1606,9 @@ func cmdParrot(client *Client, args []string, usage string) {
 	var count int
 	var report string
 	for _, c := range toParrot {
+		if !client.CanModerate(c, permissions.PermissionField["MUTE"]) {
+			continue
+		}
 		if c.Muted() != Unmuted {
 			continue
 		}
@@ -1167,20 +1625,33 @@ func cmdParrot(client *Client, args []string, usage string) {
 	report = strings.TrimSuffix(report, ", ")
 	client.SendServerMessage(fmt.Sprintf("Parroted %v clients.", count))
 	addToBuffer(client, "CMD", fmt.Sprintf("Parroted %v.", report), false)
+	if count > 0 {
+		postMuteEvent(client, fmt.Sprintf("parroted %v clients", count), report, *duration, *reason)
+	}
+}
+
+// postMuteEvent sends a mute event to the server's webhooks.
+func postMuteEvent(client *Client, action string, uids string, duration int, reason string) {
+	d := "∞"
+	if duration != -1 {
+		d = fmt.Sprintf("%v seconds", duration)
+	}
+	webhook.Post(webhook.Event{
+		Type:   webhook.EventMute,
+		Title:  fmt.Sprintf("%v %v.", client.ModName(), action),
+		Fields: []webhook.Field{{Name: "UIDs", Value: uids}, {Name: "Duration", Value: d}, {Name: "Reason", Value: reason}},
+	})
 }
 
 // Handles /log
 func cmdLog(client *Client, args []string, _ string) {
-	wantedArea, err := strconv.Atoi(args[0])
-	if err != nil {
-		client.SendServerMessage("Invalid area.")
-		return
-	}
-	for i, a := range areas {
-		if i == wantedArea {
-			client.SendServerMessage(strings.Join(a.Buffer(), "\n"))
+	if a := getAreaArg(client, strings.Join(args, " ")); a != nil {
+		if a != client.Area() && !client.HasGlobalPermission(permissions.PermissionField["LOG"]) {
+			client.SendServerMessage("You do not have permission to view that area's log.")
 			return
 		}
+		client.SendServerMessage(strings.Join(a.Buffer(), "\n"))
+		return
 	}
 	client.SendServerMessage("Invalid area.")
 }
@@ -1215,12 +1686,33 @@ func cmdDoc(client *Client, args []string, _ string) {
 }
 
 // Handles /play
-func cmdPlay(client *Client, args []string, _ string) {
+func cmdPlay(client *Client, args []string, usage string) {
 	if !client.CanChangeMusic() {
 		client.SendServerMessage("You are not allowed to change the music in this area.")
 		return
 	}
-	s := strings.Join(args, " ")
+	flags := flag.NewFlagSet("", 0)
+	flags.SetOutput(io.Discard)
+	channel := flags.Int("c", 0, "")
+	noLoop := flags.Bool("n", false, "")
+	fadeIn := flags.Bool("fi", false, "")
+	fadeOut := flags.Bool("fo", false, "")
+	flags.Parse(args)
+	if len(flags.Args()) < 1 {
+		client.SendServerMessage("Not enough arguments:\n" + usage)
+		return
+	} else if *channel < 0 || *channel >= area.MusicChannels {
+		client.SendServerMessage("Invalid channel.")
+		return
+	}
+	var effects int
+	if *fadeIn {
+		effects |= musicFadeIn
+	}
+	if *fadeOut {
+		effects |= musicFadeOut
+	}
+	s := strings.Join(flags.Args(), " ")
 
 	// Check if the song we got is a URL for streaming
 	if _, err := url.ParseRequestURI(s); err == nil {
@@ -1230,7 +1722,175 @@ func cmdPlay(client *Client, args []string, _ string) {
 			return
 		}
 	}
-	writeToArea(client.Area(), "MC", s, fmt.Sprint(client.CharID()), client.Showname(), "1", "0")
+	if *channel != 0 && !isAreaSong(client.Area(), s) {
+		client.SendServerMessage("Only songs in this area's music list can be played on other channels.")
+		return
+	} else if *channel == 0 {
+		pauseJukebox(client)
+	}
+	playMusic(client.Area(), area.Music{Name: s, Channel: *channel, Looping: !*noLoop, Effects: effects}, client.CharID(), client.Showname())
+	addToBuffer(client, "MUSIC", fmt.Sprintf("Played %v on channel %v.", s, *channel), false)
+}
+
+// Handles /jukebox
+func cmdJukebox(client *Client, args []string, usage string) {
+	j := client.Area().Jukebox()
+	if len(args) == 0 || args[0] == "list" {
+		if !j.Enabled() {
+			client.SendServerMessage("The jukebox is disabled in this area.")
+			return
+		}
+		out := "\nJukebox\n----------\n"
+		if j.Shuffle() {
+			out += "Shuffle is on.\n"
+		}
+		if s, ok := j.Current(); ok {
+			out += fmt.Sprintf("Now playing: %v (%v)\n", s.Name, s.Length)
+		}
+		queue := j.Queue()
+		if len(queue) == 0 {
+			out += "The queue is empty."
+		}
+		for i, s := range queue {
+			out += fmt.Sprintf("%v. %v (%v) - requested by [%v]\n", i+1, s.Name, s.Length, s.Requester)
+		}
+		client.SendServerMessage(out)
+		return
+	}
+	if !client.HasCMPermission() {
+		client.SendServerMessage("You do not have permission to use that command.")
+		return
+	}
+	switch args[0] {
+	case "on":
+		j.SetEnabled(true)
+		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v enabled the jukebox. Music requests will now be queued.", client.OOCName()))
+		addToBuffer(client, "CMD", "Enabled the jukebox.", false)
+	case "off":
+		j.SetEnabled(false)
+		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v disabled the jukebox.", client.OOCName()))
+		addToBuffer(client, "CMD", "Disabled the jukebox.", false)
+	case "shuffle":
+		j.SetShuffle(!j.Shuffle())
+		result := "off"
+		if j.Shuffle() {
+			result = "on"
+		}
+		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v turned jukebox shuffle %v.", client.OOCName(), result))
+		addToBuffer(client, "CMD", fmt.Sprintf("Turned jukebox shuffle %v.", result), false)
+	case "skip":
+		if _, ok := j.Current(); !ok && len(j.Queue()) == 0 {
+			client.SendServerMessage("The jukebox is not playing anything.")
+			return
+		}
+		if !advanceJukebox(client.Area()) {
+			playMusic(client.Area(), area.Music{Name: "~stop.mp3"}, -1, "")
+		}
+		addToBuffer(client, "CMD", "Skipped the current jukebox song.", false)
+	case "clear":
+		j.Clear()
+		client.SendServerMessage("Cleared the jukebox queue.")
+		addToBuffer(client, "CMD", "Cleared the jukebox queue.", false)
+	default:
+		client.SendServerMessage("Invalid command.\n" + usage)
+	}
+}
+
+// Handles /ambience
+func cmdAmbience(client *Client, args []string, _ string) {
+	if !client.CanChangeMusic() {
+		client.SendServerMessage("You are not allowed to change the music in this area.")
+		return
+	}
+	s := strings.Join(args, " ")
+	if s == "stop" {
+		playMusic(client.Area(), area.Music{Name: "~stop.mp3", Channel: area.AmbienceChannel}, client.CharID(), client.Showname())
+		addToBuffer(client, "MUSIC", "Stopped the ambience.", false)
+		return
+	} else if !isAreaSong(client.Area(), s) {
+		client.SendServerMessage("That song is not in this area's music list.")
+		return
+	}
+	playMusic(client.Area(), area.Music{Name: s, Channel: area.AmbienceChannel, Looping: true, Effects: musicFadeIn}, client.CharID(), client.Showname())
+	addToBuffer(client, "MUSIC", fmt.Sprintf("Set the ambience to %v.", s), false)
+}
+
+// Handles /timer
+func cmdTimer(client *Client, args []string, usage string) {
+	id := 1
+	if n, err := strconv.Atoi(args[0]); err == nil {
+		id = n
+		args = args[1:]
+	}
+	if len(args) < 1 {
+		client.SendServerMessage("Not enough arguments.\n" + usage)
+		return
+	}
+
+	var timer *area.Timer
+	var write func(header string, contents ...string)
+	var announce func(msg string)
+	if id == 0 {
+		if !client.HasGlobalPermission(permissions.PermissionField["MOD_SPEAK"]) {
+			client.SendServerMessage("You do not have permission to use the global timer.")
+			return
+		}
+		timer = &globalTimer
+		write = writeToAll
+		announce = func(msg string) { writeToAll("CT", encode(config.Name), encode(msg), "1") }
+	} else {
+		a := client.Area()
+		timer = a.Timer(id)
+		if timer == nil {
+			client.SendServerMessage("Invalid timer ID.")
+			return
+		}
+		write = func(header string, contents ...string) { writeToArea(a, header, contents...) }
+		announce = func(msg string) { sendAreaServerMessage(a, msg) }
+	}
+	onExpire := func(msg string) {
+		sendTimer(write, id, timer)
+		if msg != "" {
+			announce(msg)
+		}
+	}
+
+	switch args[0] {
+	case "start":
+		if !timer.Start(onExpire) {
+			client.SendServerMessage("This timer is already running or has no time set.")
+			return
+		}
+		addToBuffer(client, "CMD", fmt.Sprintf("Started timer %v.", id), false)
+	case "pause":
+		if !timer.Pause() {
+			client.SendServerMessage("This timer is not running.")
+			return
+		}
+		addToBuffer(client, "CMD", fmt.Sprintf("Paused timer %v.", id), false)
+	case "set":
+		if len(args) < 2 {
+			client.SendServerMessage("Not enough arguments.\n" + usage)
+			return
+		}
+		d, err := str2duration.ParseDuration(args[1])
+		if err != nil || d <= 0 || d > 24*time.Hour {
+			client.SendServerMessage("Invalid duration.")
+			return
+		}
+		timer.Set(d, strings.Join(args[2:], " "))
+		addToBuffer(client, "CMD", fmt.Sprintf("Set timer %v to %v.", id, d), false)
+	case "stop":
+		timer.Stop()
+		addToBuffer(client, "CMD", fmt.Sprintf("Stopped timer %v.", id), false)
+	case "hide":
+		timer.SetVisible(false)
+		addToBuffer(client, "CMD", fmt.Sprintf("Hid timer %v.", id), false)
+	default:
+		client.SendServerMessage("Invalid command.\n" + usage)
+		return
+	}
+	sendTimer(write, id, timer)
 }
 
 // Handles /testimony
@@ -1256,6 +1916,9 @@ func cmdTestimony(client *Client, args []string, _ string) {
 		client.Area().SetTstState(area.TRRecording)
 		client.SendServerMessage("Recording testimony.")
 	case "stop":
+		if client.Area().TstState() == area.TRRecording {
+			client.Area().Case().AddTestimony(client.Area().Testimony())
+		}
 		client.Area().SetTstState(area.TRIdle)
 		client.SendServerMessage("Recorder stopped.")
 		client.Area().TstJump(0)
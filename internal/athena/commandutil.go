@@ -17,7 +17,11 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>. */
 package athena
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
+
+	"github.com/MangosArentLiterature/Athena/internal/area"
 )
 
 // getUidList returns a list of clients that have the given UID(s).
@@ -46,3 +50,62 @@ func getIpidList(ipids []string) []*Client {
 	}
 	return l
 }
+
+// getAreaArg returns the area a command argument refers to, given either as the name of an area in the client's hub or as an area ID.
+// It returns nil if there is no such area.
+func getAreaArg(client *Client, s string) *area.Area {
+	for _, a := range client.Hub().Areas() {
+		if strings.EqualFold(a.Name(), s) {
+			return a
+		}
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return nil
+	}
+	return getAreaByID(id)
+}
+
+// getAreaList returns the areas within a list of areas and area ID ranges, such as "1,3-6,Courtroom 2".
+func getAreaList(client *Client, s string) ([]*area.Area, error) {
+	var l []*area.Area
+	for _, r := range strings.Split(s, ",") {
+		if a := getAreaArg(client, r); a != nil {
+			l = append(l, a)
+			continue
+		}
+		bounds := strings.SplitN(r, "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid area %v", r)
+		}
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := strconv.Atoi(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid area range %v", r)
+		}
+		for id := start; id <= end; id++ {
+			a := getAreaByID(id)
+			if a == nil {
+				return nil, fmt.Errorf("invalid area range %v", r)
+			}
+			l = append(l, a)
+		}
+	}
+	return l, nil
+}
+
+// getCharacterID returns the ID of the character with the given name, or -1 if it does not exist.
+func getCharacterID(name string) int {
+	for i, c := range characters {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,302 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package athena
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MangosArentLiterature/Athena/internal/area"
+	"github.com/MangosArentLiterature/Athena/internal/dice"
+	"github.com/MangosArentLiterature/Athena/internal/game"
+)
+
+// teamName returns the display name of a team.
+func teamName(team string) string {
+	if team == game.TeamWolves {
+		return "werewolves"
+	}
+	return "village"
+}
+
+// sendToPlayer sends a server message to a game player, if they are still connected.
+func sendToPlayer(uid int, msg string) {
+	if c, err := getClientByUid(uid); err == nil {
+		c.SendServerMessage(msg)
+	}
+}
+
+// sendRoles privately tells each player their role. Wolves are also told who the other wolves are.
+func sendRoles(g *game.Game) {
+	var wolves []string
+	for _, p := range g.Players() {
+		if p.Role.Team == game.TeamWolves {
+			wolves = append(wolves, fmt.Sprintf("[%v] %v", p.Uid, p.Name))
+		}
+	}
+	for _, p := range g.Players() {
+		msg := fmt.Sprintf("You are a %v, on the %v team.", p.Role.Name, teamName(p.Role.Team))
+		if p.Role.Description != "" {
+			msg += "\n" + p.Role.Description
+		}
+		if p.Role.Action != "" {
+			msg += "\nUse /game act <uid> at night."
+		}
+		if p.Role.Team == game.TeamWolves {
+			msg += "\nThe werewolves are: " + strings.Join(wolves, ", ")
+		}
+		sendToPlayer(p.Uid, msg)
+	}
+}
+
+// livingPlayers returns a list of a game's living players.
+func livingPlayers(g *game.Game) string {
+	var l []string
+	for _, p := range g.Players() {
+		if p.Alive {
+			l = append(l, fmt.Sprintf("[%v] %v", p.Uid, p.Name))
+		}
+	}
+	return strings.Join(l, ", ")
+}
+
+// startNight announces the start of a night and schedules it's end.
+func startNight(a *area.Area, g *game.Game) {
+	sendAreaServerMessage(a, fmt.Sprintf("Night %v falls. Players with night actions have %v to use /game act <uid>.\nLiving players: %v",
+		g.Day(), nightLength, livingPlayers(g)))
+	g.AfterPhase(nightLength, func() { endNight(a, g) })
+}
+
+// endNight resolves a night and starts the next day.
+func endNight(a *area.Area, g *game.Game) {
+	res, err := g.EndNight()
+	if err != nil {
+		return
+	}
+	for uid, target := range res.Investigations {
+		sendToPlayer(uid, fmt.Sprintf("%v is on the %v team.", target.Name, teamName(target.Role.Team)))
+	}
+	switch {
+	case res.Killed != nil:
+		msg := fmt.Sprintf("%v was killed in the night. They were a %v.", res.Killed.Name, res.Killed.Role.Name)
+		sendAreaServerMessage(a, msg)
+		addServerToBuffer(a, "GAME", msg)
+	case res.Saved:
+		sendAreaServerMessage(a, "The werewolves attacked, but their victim was saved.")
+	default:
+		sendAreaServerMessage(a, "Nobody died in the night.")
+	}
+	if g.Winner() != "" {
+		announceWinner(a, g)
+		return
+	}
+	sendAreaServerMessage(a, fmt.Sprintf("Day %v begins. Vote to lynch a player with /game vote <uid> within %v.\nLiving players: %v",
+		g.Day(), dayLength, livingPlayers(g)))
+	g.AfterPhase(dayLength, func() { endDay(a, g) })
+}
+
+// endDay resolves a day's lynch vote and starts the next night.
+func endDay(a *area.Area, g *game.Game) {
+	res, err := g.EndDay()
+	if err != nil {
+		return
+	}
+	if res.Lynched != nil {
+		msg := fmt.Sprintf("%v was lynched. They were a %v.", res.Lynched.Name, res.Lynched.Role.Name)
+		sendAreaServerMessage(a, msg)
+		addServerToBuffer(a, "GAME", msg)
+	} else {
+		sendAreaServerMessage(a, "The village could not decide, and nobody was lynched.")
+	}
+	if g.Winner() != "" {
+		announceWinner(a, g)
+		return
+	}
+	startNight(a, g)
+}
+
+// announceWinner announces the winner of a game and reveals every player's role.
+func announceWinner(a *area.Area, g *game.Game) {
+	out := "The game is over. The village wins!\n----------"
+	if g.Winner() == game.TeamWolves {
+		out = "The game is over. The werewolves win!\n----------"
+	}
+	for _, p := range g.Players() {
+		status := "alive"
+		if !p.Alive {
+			status = "dead"
+		}
+		out += fmt.Sprintf("\n[%v] %v: %v (%v)", p.Uid, p.Name, p.Role.Name, status)
+	}
+	sendAreaServerMessage(a, out)
+	addServerToBuffer(a, "GAME", fmt.Sprintf("The %v won the game.", teamName(g.Winner())))
+}
+
+// leaveGame removes a client from their area's game.
+func leaveGame(client *Client) {
+	a := client.Area()
+	g := a.Game()
+	if g == nil {
+		return
+	}
+	p, ok := g.Leave(client.Uid())
+	if !ok {
+		return
+	}
+	if g.Phase() == game.PhaseLobby {
+		sendAreaServerMessage(a, fmt.Sprintf("%v left the game.", p.Name))
+		return
+	}
+	sendAreaServerMessage(a, fmt.Sprintf("%v left the game. They were a %v.", p.Name, p.Role.Name))
+	if g.Winner() != "" {
+		announceWinner(a, g)
+	}
+}
+
+// Handles /game
+func cmdGame(client *Client, args []string, usage string) {
+	a := client.Area()
+	g := a.Game()
+	sub := "status"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch sub {
+	case "create", "start", "end":
+		if !client.HasCMPermission() {
+			client.SendServerMessage("You do not have permission to use that command.")
+			return
+		}
+	case "roles":
+	default:
+		if g == nil {
+			client.SendServerMessage("There is no game in this area.")
+			return
+		}
+	}
+
+	switch sub {
+	case "status":
+		out := fmt.Sprintf("\nWerewolf\n----------\nPhase: %v", g.Phase())
+		if g.Phase() == game.PhaseNight || g.Phase() == game.PhaseDay {
+			out += fmt.Sprintf(" %v", g.Day())
+		}
+		for _, p := range g.Players() {
+			out += fmt.Sprintf("\n[%v] %v", p.Uid, p.Name)
+			if !p.Alive {
+				out += fmt.Sprintf(" (dead, %v)", p.Role.Name)
+			}
+		}
+		if p, ok := g.Player(client.Uid()); ok && g.Phase() != game.PhaseLobby {
+			out += fmt.Sprintf("\nYour role: %v", p.Role.Name)
+		}
+		client.SendServerMessage(out)
+	case "roles":
+		out := "\nWerewolf Roles\n----------"
+		for _, r := range append(append([]game.Role{}, gameConf.Roles...), game.Villager) {
+			out += fmt.Sprintf("\n%v (%v): %v", r.Name, teamName(r.Team), r.Description)
+		}
+		client.SendServerMessage(out)
+	case "create":
+		if g != nil && g.Phase() != game.PhaseOver {
+			client.SendServerMessage("A game is already in progress.")
+			return
+		}
+		a.SetGame(game.New(gameConf))
+		a.SetStatus(area.StatusGaming)
+		sendStatusArup()
+		updateAdvert()
+		sendAreaServerMessage(a, fmt.Sprintf("%v created a game of werewolf. Join with /game join.", client.OOCName()))
+		addToBuffer(client, "GAME", "Created a game of werewolf.", false)
+	case "join":
+		if client.CharID() == -1 {
+			client.SendServerMessage("You must select a character to join the game.")
+			return
+		}
+		name := client.Showname()
+		if name == "" {
+			name = client.CurrentCharacter()
+		}
+		if err := g.Join(client.Uid(), name); err != nil {
+			client.SendServerMessage(fmt.Sprintf("Failed to join: %v.", err))
+			return
+		}
+		sendAreaServerMessage(a, fmt.Sprintf("%v joined the game. (%v players)", client.OOCName(), len(g.Players())))
+	case "leave":
+		if _, ok := g.Player(client.Uid()); !ok {
+			client.SendServerMessage("You are not playing.")
+			return
+		}
+		leaveGame(client)
+	case "start":
+		if g == nil {
+			client.SendServerMessage("There is no game in this area.")
+			return
+		}
+		if err := g.Start(dice.Intn); err != nil {
+			client.SendServerMessage(fmt.Sprintf("Failed to start: %v.", err))
+			return
+		}
+		sendAreaServerMessage(a, fmt.Sprintf("The game has started with %v players. Roles have been sent privately.", len(g.Players())))
+		addToBuffer(client, "GAME", "Started the game.", false)
+		sendRoles(g)
+		startNight(a, g)
+	case "act", "vote":
+		if len(args) < 2 {
+			client.SendServerMessage("Not enough arguments.\n" + usage)
+			return
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil {
+			client.SendServerMessage("Invalid UID.")
+			return
+		}
+		t, _ := g.Player(target)
+		if sub == "act" {
+			if err := g.Act(client.Uid(), target); err != nil {
+				client.SendServerMessage(fmt.Sprintf("Failed to act: %v.", err))
+				return
+			}
+			client.SendServerMessage(fmt.Sprintf("You chose %v.", t.Name))
+			if g.ActionsDone() {
+				endNight(a, g)
+			}
+			return
+		}
+		count, err := g.Vote(client.Uid(), target)
+		if err != nil {
+			client.SendServerMessage(fmt.Sprintf("Failed to vote: %v.", err))
+			return
+		}
+		p, _ := g.Player(client.Uid())
+		sendAreaServerMessage(a, fmt.Sprintf("%v voted to lynch %v. (%v votes)", p.Name, t.Name, count))
+		if g.Majority() {
+			endDay(a, g)
+		}
+	case "end":
+		if g == nil {
+			client.SendServerMessage("There is no game in this area.")
+			return
+		}
+		a.SetGame(nil)
+		sendAreaServerMessage(a, fmt.Sprintf("%v ended the game.", client.OOCName()))
+		addToBuffer(client, "GAME", "Ended the game.", false)
+	default:
+		client.SendServerMessage("Invalid command.\n" + usage)
+	}
+}
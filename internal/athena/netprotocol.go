@@ -23,9 +23,11 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MangosArentLiterature/Athena/internal/area"
 	"github.com/MangosArentLiterature/Athena/internal/db"
+	"github.com/MangosArentLiterature/Athena/internal/game"
 	"github.com/MangosArentLiterature/Athena/internal/logger"
 	"github.com/MangosArentLiterature/Athena/internal/packet"
 	"github.com/MangosArentLiterature/Athena/internal/sliceutil"
@@ -104,10 +106,12 @@ func pktResCount(client *Client, _ *packet.Packet) {
 		logger.LogInfo("Player limit reached")
 		client.SendPacket("BD", "This server is currently full.")
 		client.conn.Close()
+		postRaidAlert(client.Ipid(), "The server's player limit was reached.")
 		return
 	}
 	client.joining = true // This simply exists to prevent skipping the askchaa#% packet and bypassing the player count check.
-	client.SendPacket("SI", strconv.Itoa(len(characters)), strconv.Itoa(len(areas[0].Evidence())), strconv.Itoa(len(music)))
+	lobby := client.Hub().Lobby()
+	client.SendPacket("SI", strconv.Itoa(len(characters)), strconv.Itoa(len(lobby.Evidence())), strconv.Itoa(len(lobby.MusicList())))
 }
 
 // Handles RC#%
@@ -117,7 +121,11 @@ func pktReqChar(client *Client, _ *packet.Packet) {
 
 // Handles RM#%
 func pktReqAM(client *Client, _ *packet.Packet) {
-	client.write(fmt.Sprintf("SM#%v#%v#%%", areaNames, strings.Join(music, "#")))
+	hub, a := client.Hub(), client.Area()
+	if a == nil {
+		a = hub.Lobby()
+	}
+	client.write(fmt.Sprintf("SM#%v#%v#%%", strings.Join(hub.AreaNames(), "#"), strings.Join(a.MusicList(), "#")))
 }
 
 // Handles RD#%
@@ -127,17 +135,17 @@ func pktReqDone(client *Client, _ *packet.Packet) {
 	}
 	client.SetUid(uids.GetUid())
 	players.AddPlayer()
-	if config.Advertise {
-		updatePlayers <- players.GetPlayerCount()
-	}
-	client.JoinArea(areas[0])
+	updateAdvert()
+	client.JoinArea(client.Hub().Lobby())
 	client.SendPacket("DONE")
+	client.sendRecentIC()
 	sendCMArup()
 	sendStatusArup()
 	sendLockArup()
 	if config.Motd != "" {
 		client.SendServerMessage(config.Motd)
 	}
+	sendCaseListings(client, client.CasePrefs())
 	logger.LogInfof("Client (IPID:%v UID:%v) joined the server", client.Ipid(), client.Uid())
 }
 
@@ -170,6 +178,25 @@ func pktIC(client *Client, p *packet.Packet) {
 	args = append(args[:19], args[17:]...)
 	args = append(args[:20], args[18:]...)
 
+	if g := client.Area().Game(); g != nil && !client.HasCMPermission() &&
+		(g.Phase() == game.PhaseNight || g.Phase() == game.PhaseDay) {
+		if p, ok := g.Player(client.Uid()); !ok || !p.Alive {
+			client.SendServerMessage("Only living players can speak while a game is in progress.")
+			return
+		}
+	}
+	if t := client.Area().Turns(); t.Strict() && !client.HasCMPermission() {
+		if uid, ok := t.Current(); ok && uid != client.Uid() {
+			client.SendServerMessage(fmt.Sprintf("Sorry, it is currently %v's turn. Please wait for your turn to speak.", turnName(uid)))
+			return
+		}
+	}
+	if pos, ok := client.Area().Assignment(client.Uid()); ok && client.Area().RestrictPositions() {
+		args[5] = pos
+	} else if !client.CanUsePos(args[5]) {
+		client.SendServerMessage("That position is restricted to it's assigned players.")
+		return
+	}
 	client.SetPos(args[5])
 	if client.IsParrot() { // Bring out the parrot please.
 		args[4] = getParrotMsg()
@@ -188,6 +215,7 @@ func pktIC(client *Client, p *packet.Packet) {
 	if err != nil {
 		return
 	}
+	evidence := client.Area().Evidence()
 	text, err := strconv.Atoi(args[14])
 	if err != nil {
 		return
@@ -231,7 +259,7 @@ func pktIC(client *Client, p *packet.Packet) {
 		return
 	case objection < 0 || objection > 4: // objection_mod
 		return
-	case evi < 0 || evi > len(client.Area().Evidence()): // evidence
+	case evi < 0 || evi > len(evidence): // evidence
 		return
 	case args[12] != "0" && args[12] != "1": // flipping
 		return
@@ -258,13 +286,13 @@ func pktIC(client *Client, p *packet.Packet) {
 		if err != nil {
 			return
 		}
-		if pid < 0 || pid > len(characters) || pid == client.CharID() {
+		if pid < 0 || pid >= len(characters) || (pid == client.CharID() && !client.Area().DuplicateChars()) {
 			return
 		}
 		client.SetPairWantedID(pid)
 		pairing := false
 		for c := range clients.GetAllClients() {
-			if c.CharID() == pid && c.Pos() == client.Pos() && c.PairWantedID() == client.CharID() {
+			if c != client && c.Area() == client.Area() && c.CharID() == pid && c.Pos() == client.Pos() && c.PairWantedID() == client.CharID() {
 				pairinfo := c.PairInfo()
 				args[17] = pairinfo.name
 				args[18] = pairinfo.emote
@@ -363,8 +391,19 @@ func pktIC(client *Client, p *packet.Packet) {
 		client.SetShowname(args[15])
 	}
 	client.Area().SetLastSpeaker(client.CharID())
+	client.Area().AddRecentIC(args, config.ICReplay)
 	writeToArea(client.Area(), "MS", args...)
 	addToBuffer(client, "IC", "\""+args[4]+"\"", false)
+	client.Area().Turns().Touch(client.Uid())
+
+	// Case session
+	if c := client.Area().Case(); c.Active() {
+		c.Join(client.Uid(), characters[client.CharID()], client.Pos())
+		c.Log(fmt.Sprintf("%v (%v): %v", client.Showname(), client.Pos(), decode(args[4])))
+		if evi > 0 {
+			c.PresentEvidence(client.Showname(), decode(strings.Split(evidence[evi-1], "&")[0]))
+		}
+	}
 }
 
 // Handles MC#%
@@ -375,14 +414,17 @@ func pktAM(client *Client, p *packet.Packet) {
 		return
 	}
 
-	if sliceutil.ContainsString(music, p.Body[0]) {
+	if sliceutil.ContainsString(client.Area().MusicList(), p.Body[0]) {
 		if !client.CanChangeMusic() {
 			client.SendServerMessage("You are not allowed to change the music in this area.")
 			return
+		} else if client.Area().Jukebox().Enabled() && !client.HasCMPermission() {
+			queueSong(client, p.Body[0])
+			return
 		}
 		song := p.Body[0]
 		name := client.Showname()
-		effects := "0"
+		effects := 0
 		if !strings.ContainsRune(p.Body[0], '.') { // Chosen song is a category, and should stop the music.
 			song = "~stop.mp3"
 			addToBuffer(client, "MUSIC", "Stopped the music.", false)
@@ -393,17 +435,19 @@ func pktAM(client *Client, p *packet.Packet) {
 			name = p.Body[2]
 		}
 		if len(p.Body) > 3 {
-			effects = p.Body[3]
+			effects, _ = strconv.Atoi(p.Body[3])
 		}
-		writeToArea(client.Area(), "MC", song, p.Body[1], name, "1", "0", effects)
-	} else if strings.Contains(areaNames, p.Body[0]) {
+		pauseJukebox(client)
+		playMusic(client.Area(), area.Music{Name: song, Looping: true, Effects: effects}, client.CharID(), name)
+	} else if sliceutil.ContainsString(client.Hub().AreaNames(), decode(p.Body[0])) {
 		if decode(p.Body[0]) == client.Area().Name() {
 			return
 		}
-		for _, a := range areas {
+		for _, a := range client.Hub().Areas() {
 			if a.Name() == decode(p.Body[0]) {
-				if !client.ChangeArea(a) {
-					client.SendServerMessage("You are not invited to that area.")
+				if err := client.ChangeArea(a); err != nil {
+					client.SendServerMessage(fmt.Sprintf("Failed to move: %v.", err))
+					return
 				}
 				client.SendServerMessage(fmt.Sprintf("Moved to %v.", a.Name()))
 				return
@@ -427,6 +471,7 @@ func pktHP(client *Client, p *packet.Packet) {
 	if err != nil {
 		return
 	}
+	def, pro := client.Area().HP()
 	if !client.Area().SetHP(bar, value) {
 		return
 	}
@@ -440,6 +485,10 @@ func pktHP(client *Client, p *packet.Packet) {
 		side = "Prosecution"
 	}
 	addToBuffer(client, "JUD", fmt.Sprintf("Set %v HP to %v.", side, value), false)
+	client.Area().Case().ChangeHP(client.OOCName(), side, value)
+	if value == 0 && ((bar == 1 && def > 0) || (bar == 2 && pro > 0)) {
+		playGameOver(client, side)
+	}
 }
 
 // Handles RT#%
@@ -551,17 +600,14 @@ func pktModcall(client *Client, p *packet.Packet) {
 				client.Area().Name(), client.Uid(), client.CurrentCharacter(), client.Ipid(), s))
 		}
 	}
-	if enableDiscord {
-		err := webhook.PostModcall(client.CurrentCharacter(), client.Area().Name(), s)
-		if err != nil {
-			logger.LogError(err.Error())
-		}
-	}
-	logger.WriteReport(client.Area().Name(), client.Area().Buffer())
+	webhook.PostModcall(client.CurrentCharacter(), client.Area().Name(), s)
+	buffer := client.Area().Buffer()
+	webhook.PostReport(logger.WriteReport(client.Area().Name(), buffer), strings.Join(buffer, "\n"))
 }
 
 // Handles SETCASE#%
 func pktSetCase(client *Client, p *packet.Packet) {
+	old := client.CasePrefs()
 	for i, r := range p.Body[2:] {
 		if i >= 4 {
 			break
@@ -572,6 +618,14 @@ func pktSetCase(client *Client, p *packet.Packet) {
 		}
 		client.SetRoleAlert(i, b)
 	}
+	if client.Uid() != -1 {
+		// SETCASE is sent whenever a preference is toggled, so only announce listings for newly enabled roles.
+		var enabled [5]bool
+		for i, b := range client.CasePrefs() {
+			enabled[i] = b && !old[i]
+		}
+		sendCaseListings(client, enabled)
+	}
 }
 
 // Handles CASEA#%
@@ -583,25 +637,27 @@ func pktCaseAnn(client *Client, p *packet.Packet) {
 		client.SendServerMessage("You are not allowed to send case alerts in this area.")
 		return
 	}
-	newPacket := fmt.Sprintf("CASEA#CASE ANNOUNCEMENT: %v in %v needs players for %v#%v#1#%%",
-		client.CurrentCharacter(), client.Area().Name(), p.Body[0], strings.Join(p.Body[1:], "#")) // Due to a bug, old client versions require this packet to have an extra arg.
+	var roles [5]bool
+	for i, r := range p.Body[1:] {
+		if i >= len(roles) {
+			break
+		}
+		b, err := strconv.ParseBool(r)
+		if err != nil {
+			return
+		}
+		roles[i] = b
+	}
+	l := caseListing{title: p.Body[0], area: client.Area(), cm: client.CurrentCharacter(), uid: client.Uid(), roles: roles}
+	if wait, ok := addCaseListing(client.Ipid(), l); !ok {
+		client.SendServerMessage(fmt.Sprintf("You can announce another case in %v.", wait.Round(time.Second)))
+		return
+	}
+	addToBuffer(client, "CMD", fmt.Sprintf("Announced the case: %v.", l.title), false)
 
 	for c := range clients.GetAllClients() {
-		if c == client {
-			continue
-		}
-		for i, r := range p.Body[1:] {
-			if i >= 4 {
-				break
-			}
-			b, err := strconv.ParseBool(r)
-			if err != nil {
-				return
-			}
-			if b && c.AlertRole(i) {
-				c.write(newPacket)
-				break
-			}
+		if c != client && l.wants(c.CasePrefs()) {
+			c.write(l.packet())
 		}
 	}
 }
@@ -0,0 +1,93 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package athena
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MangosArentLiterature/Athena/internal/area"
+	"github.com/MangosArentLiterature/Athena/internal/sliceutil"
+)
+
+// Handles /assign
+func cmdAssign(client *Client, args []string, _ string) {
+	pos := strings.ToLower(args[1])
+	if pos != "none" && !sliceutil.ContainsString(area.Positions, pos) {
+		client.SendServerMessage(fmt.Sprintf("Invalid position. Valid positions are: %v, none.", strings.Join(area.Positions, ", ")))
+		return
+	}
+	toAssign := getUidList(strings.Split(args[0], ","))
+	var count int
+	var report string
+	for _, c := range toAssign {
+		if c.Area() != client.Area() {
+			continue
+		}
+		if pos == "none" {
+			if !client.Area().Unassign(c.Uid()) {
+				continue
+			}
+			c.SendServerMessage("Your position assignment was removed.")
+		} else {
+			client.Area().Assign(c.Uid(), pos)
+			c.SendServerMessage(fmt.Sprintf("You were assigned to %v.", pos))
+		}
+		count++
+		report += fmt.Sprintf("%v, ", c.Uid())
+	}
+	report = strings.TrimSuffix(report, ", ")
+	client.SendServerMessage(fmt.Sprintf("Assigned %v users to %v.", count, pos))
+	addToBuffer(client, "CMD", fmt.Sprintf("Assigned %v to %v.", report, pos), false)
+}
+
+// Handles /positions
+func cmdPositions(client *Client, _ []string, _ string) {
+	positions := make(map[string][]string)
+	var order []string
+	for c := range clients.GetAllClients() {
+		if c.Area() != client.Area() || c.CharID() == -1 {
+			continue
+		}
+		pos, assigned := client.Area().Assignment(c.Uid())
+		if !assigned {
+			pos = c.Pos()
+		}
+		if pos == "" {
+			continue
+		}
+		entry := fmt.Sprintf("[%v] %v", c.Uid(), c.CurrentCharacter())
+		if assigned {
+			entry += " (assigned)"
+		}
+		if _, ok := positions[pos]; !ok {
+			order = append(order, pos)
+		}
+		positions[pos] = append(positions[pos], entry)
+	}
+	if len(order) == 0 {
+		client.SendServerMessage("Nobody holds a position in this area.")
+		return
+	}
+	sort.Strings(order)
+	out := "\nPositions\n----------"
+	for _, pos := range order {
+		out += fmt.Sprintf("\n%v: %v", pos, strings.Join(positions[pos], ", "))
+	}
+	client.SendServerMessage(out)
+}
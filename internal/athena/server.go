@@ -21,15 +21,17 @@ import (
 	"crypto/md5"
 	"encoding/base64"
 	"fmt"
-	"math/rand"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/MangosArentLiterature/Athena/internal/area"
 	"github.com/MangosArentLiterature/Athena/internal/db"
+	"github.com/MangosArentLiterature/Athena/internal/dice"
+	"github.com/MangosArentLiterature/Athena/internal/game"
 	"github.com/MangosArentLiterature/Athena/internal/logger"
 	"github.com/MangosArentLiterature/Athena/internal/ms"
 	"github.com/MangosArentLiterature/Athena/internal/permissions"
@@ -37,29 +39,64 @@ import (
 	"github.com/MangosArentLiterature/Athena/internal/settings"
 	"github.com/MangosArentLiterature/Athena/internal/sliceutil"
 	"github.com/MangosArentLiterature/Athena/internal/uidmanager"
+	"github.com/MangosArentLiterature/Athena/internal/vote"
 	"github.com/MangosArentLiterature/Athena/internal/webhook"
-	"github.com/ecnepsnai/discord"
 	"github.com/xhit/go-str2duration/v2"
 	"nhooyr.io/websocket"
 )
 
 const version = "v1.0.2"
 
+// Music effect flags used in MC packets.
+const (
+	musicFadeIn  = 1
+	musicFadeOut = 2
+)
+
 var (
 	config                                 *settings.Config
 	characters, music, backgrounds, parrot []string
 	areas                                  []*area.Area
-	areaNames                              string
+	hubs                                   []*area.Hub
+	tempAreas                              = make(map[*area.Area]struct{})
+	areaTemplate                           area.AreaData
+	templateEviMode                        area.EvidenceMode
+	templateMusic, templateBgs             []string
+	templateDeck                           []string
+	templateTurnTimeout                    time.Duration
+	tables                                 []dice.Table
+	areasMu                                sync.RWMutex
+	nextAreaID                             int // The ID given to the next temporary area. IDs are never reused.
+	globalTimer                            area.Timer
+	globalPoll                             *vote.Vote
+	pollMu                                 sync.Mutex
+	songLengths                            map[string]time.Duration
 	roles                                  []permissions.Role
 	uids                                   uidmanager.UidManager
 	players                                playercount.PlayerCount
-	enableDiscord                          bool
 	clients                                ClientList = ClientList{list: make(map[*Client]struct{})}
-	updatePlayers                                     = make(chan int)      // Updates the advertiser's player count.
-	advertDone                                        = make(chan struct{}) // Signals the advertiser to stop.
-	FatalError                                        = make(chan error)    // Signals that the server should stop after a fatal error.
+	advertiser                             *ms.Advertiser
+	FatalError                             = make(chan error) // Signals that the server should stop after a fatal error.
+	caseExpiry, caseCooldown, juryTimeout  time.Duration
+	gameConf                               game.Config
+	dayLength, nightLength                 time.Duration
+	lastRaidAlert                          time.Time
+	raidAlertMu                            sync.Mutex
+	joinAttempts                           = make(map[string]joinAttempt)
+	joinAttemptsMu                         sync.Mutex
 )
 
+const (
+	maxJoinAttempts = 5           // Incorrect area passwords an IPID may try before having to wait.
+	joinCooldown    = time.Minute // How long an IPID must wait once it runs out of attempts.
+)
+
+// joinAttempt tracks an IPID's incorrect area passwords since it's first failure.
+type joinAttempt struct {
+	count int
+	since time.Time
+}
+
 // InitServer initalizes the server's database, uids, configs, and advertiser.
 func InitServer(conf *settings.Config) error {
 	db.Open()
@@ -68,7 +105,7 @@ func InitServer(conf *settings.Config) error {
 
 	// Load server data.
 	var err error
-	music, err = settings.LoadMusic()
+	music, songLengths, err = settings.LoadMusic("/music.txt")
 	if err != nil {
 		return err
 	}
@@ -78,14 +115,19 @@ func InitServer(conf *settings.Config) error {
 	} else if len(characters) == 0 {
 		return fmt.Errorf("empty character list")
 	}
-	areaData, err := settings.LoadAreas()
+	areaConf, err := settings.LoadAreas()
 	if err != nil {
 		return err
 	}
 
-	roles, err = settings.LoadRoles()
+	var cmdOverrides map[string]string
+	roles, cmdOverrides, err = settings.LoadRoles()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load roles: %v", err)
+	}
+	err = applyCommandOverrides(cmdOverrides)
+	if err != nil {
+		return fmt.Errorf("failed to load roles: %v", err)
 	}
 
 	backgrounds, err = settings.LoadFile("/backgrounds.txt")
@@ -105,46 +147,144 @@ func InitServer(conf *settings.Config) error {
 	if err != nil {
 		return fmt.Errorf("failed to parse default_ban_duration: %v", err.Error())
 	}
+	caseExpiry, err = str2duration.ParseDuration(conf.CaseExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to parse case_listing_expiry: %v", err.Error())
+	}
+	caseCooldown, err = str2duration.ParseDuration(conf.CaseCooldown)
+	if err != nil {
+		return fmt.Errorf("failed to parse case_announce_cooldown: %v", err.Error())
+	}
+	juryTimeout, err = str2duration.ParseDuration(conf.JuryTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to parse jury_timeout: %v", err.Error())
+	}
+	gameConf, err = settings.LoadGames()
+	if err != nil {
+		return fmt.Errorf("failed to load games: %v", err)
+	}
+	dayLength, err = str2duration.ParseDuration(gameConf.DayLength)
+	if err != nil {
+		return fmt.Errorf("failed to parse day_length: %v", err.Error())
+	}
+	nightLength, err = str2duration.ParseDuration(gameConf.NightLength)
+	if err != nil {
+		return fmt.Errorf("failed to parse night_length: %v", err.Error())
+	}
+	if conf.EnableMSHost {
+		_, err = str2duration.ParseDuration(conf.MSHostExpiry)
+		if err != nil {
+			return fmt.Errorf("failed to parse master server expiry: %v", err.Error())
+		}
+	}
 
-	// Discord webhook.
-	if config.WebhookURL != "" {
-		enableDiscord = true
-		webhook.ServerName = config.Name
-		discord.WebhookURL = config.WebhookURL
+	// Load hubs.
+	if len(areaConf.Hubs) == 0 {
+		areaConf.Hubs = append(areaConf.Hubs, area.HubData{Name: "Main"})
+	}
+	for _, h := range areaConf.Hubs {
+		if h.Name == "" {
+			return fmt.Errorf("hub has no name")
+		}
+		if getHubByName(h.Name) != nil {
+			return fmt.Errorf("duplicate hub %v", h.Name)
+		}
+		for user, role := range h.Moderators {
+			if _, err := getRole(role); err != nil {
+				return fmt.Errorf("hub %v gives %v nonexistent role %v", h.Name, user, role)
+			}
+		}
+		hubs = append(hubs, area.NewHub(h))
 	}
 
 	// Load areas.
-	for _, a := range areaData {
-		areaNames += a.Name + "#"
-		var evi_mode area.EvidenceMode
-		switch strings.ToLower(a.Evi_mode) {
-		case "any":
-			evi_mode = area.EviAny
-		case "cms":
-			evi_mode = area.EviCMs
-		case "mods":
-			evi_mode = area.EviMods
-		default:
-			logger.LogWarningf("Area %v has an invalid or undefined evidence mode, defaulting to 'cms'.", a.Name)
-			evi_mode = area.EviCMs
+	for _, a := range areaConf.Areas {
+		hub := hubs[0]
+		if a.Hub != "" {
+			hub = getHubByName(a.Hub)
+			if hub == nil {
+				return fmt.Errorf("area %v belongs to nonexistent hub %v", a.Name, a.Hub)
+			}
+		}
+		musicList, bgList, err := loadAreaLists(a)
+		if err != nil {
+			return fmt.Errorf("failed to load lists for area %v: %v", a.Name, err)
 		}
-		if a.Bg == "" || !sliceutil.ContainsString(backgrounds, a.Bg) {
+		if a.Bg == "" || !sliceutil.ContainsString(bgList, a.Bg) {
 			logger.LogWarningf("Area %v has an invalid or undefined background, defaulting to 'default'.", a.Name)
 			a.Bg = "default"
 		}
-		areas = append(areas, area.NewArea(a, len(characters), conf.BufSize, evi_mode))
+		turnTimeout, err := parseTurnTimeout(a)
+		if err != nil {
+			return fmt.Errorf("failed to parse turn_timeout for area %v: %v", a.Name, err)
+		}
+		newArea := area.NewArea(a, len(characters), conf.BufSize, parseEviMode(a))
+		newArea.SetDefaultTurnTimeout(turnTimeout)
+		newArea.SetMusicList(musicList)
+		newArea.SetBackgrounds(bgList)
+		deck, err := loadDeck(a)
+		if err != nil {
+			return fmt.Errorf("failed to load deck for area %v: %v", a.Name, err)
+		}
+		newArea.Deck().SetCards(deck)
+		newArea.SetID(len(areas))
+		areas = append(areas, newArea)
+		hub.AddArea(newArea)
+	}
+	nextAreaID = len(areas)
+	for _, h := range hubs {
+		if len(h.Areas()) == 0 {
+			return fmt.Errorf("hub %v has no areas", h.Name())
+		}
+	}
+	areaTemplate = areaConf.Template
+	areaTemplate.Name = "Template"
+	templateEviMode = parseEviMode(areaTemplate)
+	templateMusic, templateBgs, err = loadAreaLists(areaTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to load lists for area template: %v", err)
 	}
-	areaNames = strings.TrimSuffix(areaNames, "#")
+	if areaTemplate.Bg == "" || !sliceutil.ContainsString(templateBgs, areaTemplate.Bg) {
+		areaTemplate.Bg = "default"
+	}
+	templateDeck, err = loadDeck(areaTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to load deck for area template: %v", err)
+	}
+	templateTurnTimeout, err = parseTurnTimeout(areaTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse turn_timeout for area template: %v", err)
+	}
+	tables, err = settings.LoadTables()
+	if err != nil {
+		return fmt.Errorf("failed to load tables: %v", err)
+	}
+
+	// Webhooks.
+	webhook.ServerName = config.Name
+	hooks := config.Webhooks
+	if config.WebhookURL != "" {
+		hooks = append(hooks, webhook.Webhook{URL: config.WebhookURL, Format: "discord", Events: []string{"modcall", "report"}})
+	}
+	err = webhook.Start(hooks)
+	if err != nil {
+		return fmt.Errorf("failed to start webhooks: %v", err)
+	}
+	webhook.Post(webhook.Event{Type: webhook.EventStart, Title: fmt.Sprintf("%v has started.", config.Name)})
+
 	if config.Advertise {
 		advert := ms.Advertisement{
 			Port:    config.Port,
 			Players: players.GetPlayerCount(),
 			Name:    config.Name,
-			Desc:    config.Desc}
+			Desc:    config.Desc,
+			Areas:   len(areas),
+			Version: version}
 		if config.EnableWS {
 			advert.WSPort = config.WSPort
 		}
-		go ms.Advertise(config.MSAddr, advert, updatePlayers, advertDone)
+		advertiser = ms.NewAdvertiser(append([]string{config.MSAddr}, config.ExtraMSAddrs...), advert)
+		advertiser.Start()
 	}
 	return nil
 }
@@ -191,6 +331,26 @@ func ListenWS() {
 	}
 }
 
+// ListenMS starts the server's built-in master server.
+func ListenMS() {
+	listener, err := net.Listen("tcp", config.Addr+":"+strconv.Itoa(config.MSHostPort))
+	if err != nil {
+		FatalError <- err
+		return
+	}
+	logger.LogDebug("Master server listener started.")
+	defer listener.Close()
+
+	expiry, _ := str2duration.ParseDuration(config.MSHostExpiry)
+	mux := http.NewServeMux()
+	mux.Handle("/servers", ms.NewServer(config.MSHostSecret, expiry))
+	s := &http.Server{Handler: mux}
+	err = s.Serve(listener)
+	if err != http.ErrServerClosed {
+		FatalError <- err
+	}
+}
+
 // HandleWS handles a websocket connection.
 func HandleWS(w http.ResponseWriter, r *http.Request) {
 	c, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: []string{"web.aceattorneyonline.com"}}) // WS connections not originating from webAO will be rejected.
@@ -225,6 +385,24 @@ func writeToArea(area *area.Area, header string, contents ...string) {
 	}
 }
 
+// writeCharsCheck sends each client in an area the characters they cannot use.
+func writeCharsCheck(a *area.Area) {
+	for client := range clients.GetAllClients() {
+		if client.Area() == a {
+			client.sendCharsCheck()
+		}
+	}
+}
+
+// writeToHub sends a message to all clients in a given hub.
+func writeToHub(hub *area.Hub, header string, contents ...string) {
+	for client := range clients.GetAllClients() {
+		if client.Area() != nil && hub.HasArea(client.Area()) {
+			client.SendPacket(header, contents...)
+		}
+	}
+}
+
 // addToBuffer writes to an area buffer according to a client's action.
 func addToBuffer(client *Client, action string, message string, audit bool) {
 	s := fmt.Sprintf("%v | %v | %v | %v | %v | %v",
@@ -237,18 +415,32 @@ func addToBuffer(client *Client, action string, message string, audit bool) {
 
 // sendPlayerArup sends a player ARUP to all connected clients.
 func sendPlayerArup() {
+	for _, h := range hubs {
+		writeToHub(h, "ARUP", playerArup(h)...)
+	}
+}
+
+// playerArup returns the body of a player ARUP for a hub.
+func playerArup(hub *area.Hub) []string {
 	plCounts := []string{"0"}
-	for _, a := range areas {
+	for _, a := range hub.Areas() {
 		s := strconv.Itoa(a.PlayerCount())
 		plCounts = append(plCounts, s)
 	}
-	writeToAll("ARUP", plCounts...)
+	return plCounts
 }
 
 // sendCMArup sends a CM ARUP to all connected clients.
 func sendCMArup() {
+	for _, h := range hubs {
+		writeToHub(h, "ARUP", cmArup(h)...)
+	}
+}
+
+// cmArup returns the body of a CM ARUP for a hub.
+func cmArup(hub *area.Hub) []string {
 	returnL := []string{"2"}
-	for _, a := range areas {
+	for _, a := range hub.Areas() {
 		var cms []string
 		var uids []int
 		uids = append(uids, a.CMs()...)
@@ -265,25 +457,307 @@ func sendCMArup() {
 		}
 		returnL = append(returnL, strings.Join(cms, ", "))
 	}
-	writeToAll("ARUP", returnL...)
+	return returnL
+}
+
+// updateAdvert updates the server's advertisement with the current player count and area statuses.
+func updateAdvert() {
+	if advertiser == nil {
+		return
+	}
+	playerCount := players.GetPlayerCount()
+	var casing int
+	allAreas := getAreas()
+	for _, a := range allAreas {
+		if a.Status() == area.StatusCasing {
+			casing++
+		}
+	}
+	advertiser.Update(func(advert *ms.Advertisement) {
+		advert.Players = playerCount
+		advert.Areas = len(allAreas)
+		advert.Casing = casing
+	})
 }
 
 // sendStatusArup sends a status ARUP to all connected clients.
 func sendStatusArup() {
+	for _, h := range hubs {
+		writeToHub(h, "ARUP", statusArup(h)...)
+	}
+}
+
+// statusArup returns the body of a status ARUP for a hub.
+func statusArup(hub *area.Hub) []string {
 	statuses := []string{"1"}
-	for _, a := range areas {
+	for _, a := range hub.Areas() {
 		statuses = append(statuses, a.Status().String())
 	}
-	writeToAll("ARUP", statuses...)
+	return statuses
 }
 
 // sendLockArup sends a lock ARUP to all connected clients.
 func sendLockArup() {
+	for _, h := range hubs {
+		writeToHub(h, "ARUP", lockArup(h)...)
+	}
+}
+
+// lockArup returns the body of a lock ARUP for a hub.
+func lockArup(hub *area.Hub) []string {
 	locks := []string{"3"}
-	for _, a := range areas {
+	for _, a := range hub.Areas() {
 		locks = append(locks, a.Lock().String())
 	}
-	writeToAll("ARUP", locks...)
+	return locks
+}
+
+// sendHubArups sends a client the area statuses of it's current hub.
+func sendHubArups(client *Client) {
+	hub := client.Hub()
+	client.SendPacket("ARUP", statusArup(hub)...)
+	client.SendPacket("ARUP", cmArup(hub)...)
+	client.SendPacket("ARUP", lockArup(hub)...)
+}
+
+// getHub returns the hub containing the given area.
+func getHub(a *area.Area) *area.Hub {
+	for _, h := range hubs {
+		if h.HasArea(a) {
+			return h
+		}
+	}
+	return hubs[0]
+}
+
+// getHubByName returns the hub with the given name, or nil if it does not exist.
+func getHubByName(name string) *area.Hub {
+	for _, h := range hubs {
+		if strings.EqualFold(h.Name(), name) {
+			return h
+		}
+	}
+	return nil
+}
+
+// loadDeck returns an area's deck of cards, or nil if it uses a standard deck.
+func loadDeck(a area.AreaData) ([]string, error) {
+	if a.Deck_file == "" {
+		return nil, nil
+	}
+	lines, err := settings.LoadFile("/" + a.Deck_file)
+	if err != nil {
+		return nil, err
+	}
+	var cards []string
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			cards = append(cards, l)
+		}
+	}
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("empty deck")
+	}
+	return cards, nil
+}
+
+// loadAreaLists returns an area's music and background lists.
+func loadAreaLists(a area.AreaData) ([]string, []string, error) {
+	musicList, bgList := music, backgrounds
+	if a.Music_file != "" {
+		var lengths map[string]time.Duration
+		var err error
+		musicList, lengths, err = settings.LoadMusic("/" + a.Music_file)
+		if err != nil {
+			return nil, nil, err
+		}
+		for song, length := range lengths {
+			songLengths[song] = length
+		}
+	}
+	if len(a.Music_cats) > 0 {
+		musicList = filterMusic(musicList, a.Music_cats)
+		if len(musicList) == 0 {
+			return nil, nil, fmt.Errorf("no music in categories %v", strings.Join(a.Music_cats, ", "))
+		}
+	}
+	if a.Bg_file != "" {
+		var err error
+		bgList, err = settings.LoadFile("/" + a.Bg_file)
+		if err != nil {
+			return nil, nil, err
+		} else if len(bgList) == 0 {
+			return nil, nil, fmt.Errorf("empty background list")
+		}
+	}
+	return musicList, bgList, nil
+}
+
+// filterMusic returns the given categories of a music list, along with their songs.
+func filterMusic(list []string, categories []string) []string {
+	var l []string
+	var include bool
+	for _, s := range list {
+		if !strings.ContainsRune(s, '.') {
+			include = sliceutil.ContainsString(categories, s)
+		}
+		if include {
+			l = append(l, s)
+		}
+	}
+	return l
+}
+
+// parseTurnTimeout returns an area's configured turn timeout, where an empty value never skips idle players.
+func parseTurnTimeout(a area.AreaData) (time.Duration, error) {
+	if a.Turn_timeout == "" {
+		return 0, nil
+	}
+	return str2duration.ParseDuration(a.Turn_timeout)
+}
+
+// parseEviMode returns an area's configured evidence mode.
+func parseEviMode(a area.AreaData) area.EvidenceMode {
+	switch strings.ToLower(a.Evi_mode) {
+	case "any":
+		return area.EviAny
+	case "cms":
+		return area.EviCMs
+	case "mods":
+		return area.EviMods
+	default:
+		logger.LogWarningf("Area %v has an invalid or undefined evidence mode, defaulting to 'cms'.", a.Name)
+		return area.EviCMs
+	}
+}
+
+// getAreas returns all areas, including temporary areas.
+func getAreas() []*area.Area {
+	areasMu.RLock()
+	defer areasMu.RUnlock()
+	return append([]*area.Area{}, areas...)
+}
+
+// getAreaByID returns the area with the given id, or nil if it does not exist.
+func getAreaByID(id int) *area.Area {
+	areasMu.RLock()
+	defer areasMu.RUnlock()
+	for _, a := range areas {
+		if a.ID() == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// areaRef returns the reference used to store an area in the database.
+func areaRef(a *area.Area) db.AreaRef {
+	return db.AreaRef{Hub: getHub(a).Name(), Area: a.Name()}
+}
+
+// getAreaByRef returns the configured area a database reference points to, or nil if it no longer exists.
+// Temporary areas are never returned, as their names are chosen by players and may be reused.
+func getAreaByRef(ref db.AreaRef) *area.Area {
+	h := getHubByName(ref.Hub)
+	if h == nil {
+		return nil
+	}
+	for _, a := range h.Areas() {
+		if a.Name() == ref.Area && !isTempArea(a) {
+			return a
+		}
+	}
+	return nil
+}
+
+// isTempArea returns whether the given area is a temporary area.
+func isTempArea(a *area.Area) bool {
+	areasMu.RLock()
+	defer areasMu.RUnlock()
+	_, ok := tempAreas[a]
+	return ok
+}
+
+// makeTempArea creates a temporary area in the given hub.
+func makeTempArea(hub *area.Hub, name string) (*area.Area, error) {
+	areasMu.Lock()
+	if len(tempAreas) >= config.MaxTempAreas {
+		areasMu.Unlock()
+		return nil, fmt.Errorf("the maximum number of temporary areas has been reached")
+	}
+	for _, n := range hub.AreaNames() {
+		if strings.EqualFold(n, name) {
+			areasMu.Unlock()
+			return nil, fmt.Errorf("an area with that name already exists")
+		}
+	}
+	data := areaTemplate
+	data.Name, data.Hub = name, hub.Name()
+	a := area.NewArea(data, len(characters), config.BufSize, templateEviMode)
+	a.SetMusicList(templateMusic)
+	a.SetBackgrounds(templateBgs)
+	a.Deck().SetCards(templateDeck)
+	a.SetDefaultTurnTimeout(templateTurnTimeout)
+	a.SetID(nextAreaID)
+	nextAreaID++
+	areas = append(areas, a)
+	tempAreas[a] = struct{}{}
+	hub.AddArea(a)
+	areasMu.Unlock()
+	sendAreaList(hub)
+	updateAdvert()
+	return a, nil
+}
+
+// addToArea adds a client to an area, returning false if the area has been removed.
+// If another client took the client's character in the meantime, the client joins as a spectator.
+// Temporary areas are only removed while empty and under areasMu, so a joined area is never removed underneath the client.
+func addToArea(a *area.Area, client *Client) bool {
+	areasMu.RLock()
+	defer areasMu.RUnlock()
+	for _, x := range areas {
+		if x == a {
+			if !a.AddChar(client.CharID()) {
+				client.SetCharID(-1)
+				a.AddChar(-1)
+				client.SendServerMessage("Your character was taken by another player.")
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// removeTempArea destroys a temporary area if it is empty.
+func removeTempArea(a *area.Area) {
+	hub := getHub(a)
+	areasMu.Lock()
+	if _, ok := tempAreas[a]; !ok || a.PlayerCount() > 0 {
+		areasMu.Unlock()
+		return
+	}
+	delete(tempAreas, a)
+	for i, x := range areas {
+		if x == a {
+			areas = append(areas[:i], areas[i+1:]...)
+			break
+		}
+	}
+	hub.RemoveArea(a)
+	areasMu.Unlock()
+	a.Reset()
+	logger.LogInfof("Temporary area %v was removed.", a.Name())
+	sendAreaList(hub)
+	updateAdvert()
+}
+
+// sendAreaList sends the area list and area statuses of a hub to all clients in the hub.
+func sendAreaList(hub *area.Hub) {
+	writeToHub(hub, "FA", hub.AreaNames()...)
+	writeToHub(hub, "ARUP", playerArup(hub)...)
+	writeToHub(hub, "ARUP", statusArup(hub)...)
+	writeToHub(hub, "ARUP", cmArup(hub)...)
+	writeToHub(hub, "ARUP", lockArup(hub)...)
 }
 
 // getRole returns the role with the corresponding name, or an error if the role does not exist.
@@ -296,6 +770,35 @@ func getRole(name string) (permissions.Role, error) {
 	return permissions.Role{}, fmt.Errorf("role does not exist")
 }
 
+// getAreaPerms returns a moderator user's area-scoped permissions.
+func getAreaPerms(username string) map[*area.Area]uint64 {
+	perms, err := db.GetAreaPermissions(username)
+	if err != nil {
+		logger.LogErrorf("while getting area permissions for %v: %v", username, err)
+		return nil
+	}
+	areaPerms := make(map[*area.Area]uint64)
+	for ref, p := range perms {
+		if a := getAreaByRef(ref); a != nil {
+			areaPerms[a] = p
+		}
+	}
+	return areaPerms
+}
+
+// getHubPerms returns the permissions a moderator user has in every area of a hub, including areas created after they logged in.
+func getHubPerms(h *area.Hub, username string) uint64 {
+	roleName, ok := h.Moderators()[username]
+	if !ok {
+		return 0
+	}
+	role, err := getRole(roleName)
+	if err != nil {
+		return 0
+	}
+	return role.GetPermissions()
+}
+
 // getClientByUid returns the client with the given uid.
 func getClientByUid(uid int) (*Client, error) {
 	for c := range clients.GetAllClients() {
@@ -322,11 +825,215 @@ func sendAreaServerMessage(area *area.Area, message string) {
 	writeToArea(area, "CT", encode(config.Name), encode(message), "1")
 }
 
+// playMusic plays music on one of an area's channels.
+func playMusic(a *area.Area, m area.Music, charID int, showname string) {
+	if m.Name == "~stop.mp3" {
+		a.SetMusic(area.Music{Channel: m.Channel})
+	} else {
+		a.SetMusic(m)
+	}
+	writeToArea(a, "MC", musicPacket(m, charID, showname)...)
+}
+
+// queueSong adds a song to the jukebox of a client's area, starting the jukebox if it is idle.
+func queueSong(client *Client, song string) {
+	if !strings.ContainsRune(song, '.') {
+		client.SendServerMessage("Categories cannot be queued.")
+		return
+	}
+	length, ok := songLengths[song]
+	if !ok {
+		client.SendServerMessage("That song cannot be queued, as it has no declared length.")
+		return
+	}
+	a := client.Area()
+	if a.Jukebox().Add(area.Song{Name: song, Length: length, Requester: client.Uid()}) {
+		advanceJukebox(a)
+	}
+	client.SendServerMessage(fmt.Sprintf("Added %v to the jukebox queue.", song))
+	addToBuffer(client, "MUSIC", fmt.Sprintf("Queued %v.", song), false)
+}
+
+// isAreaSong returns whether a song is in an area's music list, as opposed to a category or an arbitrary string.
+func isAreaSong(a *area.Area, song string) bool {
+	return strings.ContainsRune(song, '.') && sliceutil.ContainsString(a.MusicList(), song)
+}
+
+// pauseJukebox stops an area's jukebox when a CM plays music over it, so the jukebox doesn't replace their song.
+func pauseJukebox(client *Client) {
+	if client.Area().Jukebox().Stop() {
+		sendAreaServerMessage(client.Area(), fmt.Sprintf("%v paused the jukebox. Use /jukebox skip to resume it.", client.OOCName()))
+	}
+}
+
+// advanceJukebox plays the next song in an area's jukebox. It returns false if the queue is empty.
+func advanceJukebox(a *area.Area) bool {
+	return a.Jukebox().Next(func(s area.Song) {
+		playMusic(a, area.Music{Name: s.Name}, -1, "")
+		sendAreaServerMessage(a, fmt.Sprintf("Now playing: %v", s.Name))
+	})
+}
+
+// playGameOver plays the penalty sequence of a client's area after a side's penalty bar reaches 0.
+func playGameOver(client *Client, side string) {
+	a := client.Area()
+	seq, ok := a.GameOver()
+	if !ok {
+		return
+	}
+	if seq.Animation != "" {
+		writeToArea(a, "RT", strings.Split(seq.Animation, "#")...)
+	}
+	if seq.Music != "" {
+		playMusic(a, area.Music{Name: seq.Music}, -1, "")
+	}
+	msg := seq.Message
+	if msg == "" {
+		msg = fmt.Sprintf("The %v has run out of penalty!", strings.ToLower(side))
+	}
+	sendAreaServerMessage(a, msg)
+	verdict := "Not guilty"
+	if side == "Defense" {
+		verdict = "Guilty"
+	}
+	addToBuffer(client, "VERDICT", fmt.Sprintf("%v. The %v's penalty bar was depleted.", verdict, strings.ToLower(side)), false)
+	a.Case().SetVerdict(verdict)
+	if seq.Lock {
+		a.SetJudgeLocked(true)
+		sendAreaServerMessage(a, "Judge controls are locked until a CM resets the penalty bars with /resethp.")
+	}
+}
+
+// endCase ends an area's case session, writing it's transcript and adding it to the database.
+// It returns the transcript's file name, or false if no case is in session.
+func endCase(a *area.Area) (string, bool) {
+	rec, ok := a.Case().End()
+	if !ok {
+		return "", false
+	}
+	fname := logger.WriteCase(a.Name(), rec.Transcript())
+	_, err := db.AddCase(db.CaseInfo{
+		Title:        rec.Title,
+		Area:         rec.Area,
+		Host:         rec.Host,
+		Start:        rec.Start.Unix(),
+		End:          rec.End.Unix(),
+		Verdict:      rec.Verdict,
+		Participants: rec.ParticipantNames(),
+		Transcript:   fname,
+	})
+	if err != nil {
+		logger.LogErrorf("Error saving case in %v: %v", a.Name(), err)
+	}
+	return fname, true
+}
+
+// addServerToBuffer writes a server event to an area buffer.
+func addServerToBuffer(a *area.Area, action string, message string) {
+	a.UpdateBuffer(fmt.Sprintf("%v | %v | %v | %v | %v | %v",
+		time.Now().UTC().Format("15:04:05"), action, "Server", "-", config.Name, message))
+}
+
+// voteResults returns a readable tally of a vote.
+func voteResults(title string, v *vote.Vote) string {
+	out := fmt.Sprintf("\n%v: %v\n----------", title, v.Question())
+	for i, r := range v.Results() {
+		out += fmt.Sprintf("\n%v. %v: %v", i+1, r.Option, r.Count)
+		if len(r.Voters) > 0 {
+			out += fmt.Sprintf(" (%v)", strings.Join(r.Voters, ", "))
+		}
+	}
+	if !v.Open() {
+		winners := v.Winners()
+		switch len(winners) {
+		case 0:
+			out += "\nNo votes were cast."
+		case 1:
+			out += "\nResult: " + winners[0]
+		default:
+			out += "\nResult: Tie between " + strings.Join(winners, ", ")
+		}
+	}
+	return out
+}
+
+// voteTally returns a one line tally of a vote, for use in area buffers.
+func voteTally(v *vote.Vote) string {
+	var tally []string
+	for _, r := range v.Results() {
+		tally = append(tally, fmt.Sprintf("%v: %v", r.Option, r.Count))
+	}
+	return strings.Join(tally, ", ")
+}
+
+// closeJury announces the results of an area's closed jury vote.
+func closeJury(a *area.Area, v *vote.Vote) {
+	sendAreaServerMessage(a, voteResults("Jury Vote", v))
+	s := fmt.Sprintf("Jury vote \"%v\" closed. %v.", v.Question(), voteTally(v))
+	addServerToBuffer(a, "JURY", s)
+	a.Case().Log(s)
+}
+
+// turnName returns the name shown for a UID in turn order messages.
+func turnName(uid int) string {
+	c, err := getClientByUid(uid)
+	if err != nil {
+		return fmt.Sprintf("[%v]", uid)
+	}
+	return fmt.Sprintf("[%v] %v", uid, c.OOCName())
+}
+
+// advanceTurn passes an area's turn to the next player in it's turn order.
+func advanceTurn(a *area.Area) {
+	uid, ok := a.Turns().Next(func(idle int, stalled bool) {
+		if stalled {
+			sendAreaServerMessage(a, fmt.Sprintf("%v was idle. Everyone in the turn order has been idle for a full round, so turns will no longer be skipped until a CM uses /turns next.", turnName(idle)))
+			return
+		}
+		sendAreaServerMessage(a, fmt.Sprintf("%v was idle, and their turn was skipped.", turnName(idle)))
+		advanceTurn(a)
+	})
+	if !ok {
+		sendAreaServerMessage(a, "The turn order is empty.")
+		return
+	}
+	sendAreaServerMessage(a, fmt.Sprintf("It is now %v's turn.", turnName(uid)))
+}
+
+// musicPacket returns the body of an MC packet for the given music.
+func musicPacket(m area.Music, charID int, showname string) []string {
+	looping := "0"
+	if m.Looping {
+		looping = "1"
+	}
+	return []string{m.Name, strconv.Itoa(charID), showname, looping, strconv.Itoa(m.Channel), strconv.Itoa(m.Effects)}
+}
+
+// sendTimer sends a timer's state using the given write function.
+func sendTimer(write func(header string, contents ...string), id int, t *area.Timer) {
+	remaining, running, visible := t.State()
+	if !visible {
+		write("TI", strconv.Itoa(id), "3", "0")
+		return
+	}
+	cmd := "1"
+	if running {
+		cmd = "0"
+	}
+	write("TI", strconv.Itoa(id), "2", "0")
+	write("TI", strconv.Itoa(id), cmd, strconv.FormatInt(remaining.Milliseconds(), 10))
+}
+
 // CleanupServer closes all connections to the server, and closes the server's database.
 func CleanupServer() {
 	for client := range clients.GetAllClients() {
 		client.conn.Close()
 	}
+	if advertiser != nil {
+		advertiser.Stop()
+	}
+	webhook.Post(webhook.Event{Type: webhook.EventStop, Title: fmt.Sprintf("%v has stopped.", config.Name)})
+	webhook.Stop(5 * time.Second)
 	db.Close()
 }
 
@@ -340,8 +1047,58 @@ func getIpid(s string) string {
 	return ipid[:len(ipid)-2] // Removes the trailing padding.
 }
 
+// postRaidAlert sends a raid alert to the server's webhooks, at most once per minute.
+func postRaidAlert(ipid string, reason string) {
+	raidAlertMu.Lock()
+	defer raidAlertMu.Unlock()
+	if time.Since(lastRaidAlert) < time.Minute {
+		return
+	}
+	lastRaidAlert = time.Now()
+	webhook.Post(webhook.Event{
+		Type:        webhook.EventRaid,
+		Title:       "Possible raid detected.",
+		Description: reason,
+		Fields:      []webhook.Field{{Name: "IPID", Value: ipid}},
+	})
+}
+
+// joinAttemptWait returns how long an IPID must wait before trying another area password, or 0 if it may try now.
+func joinAttemptWait(ipid string) time.Duration {
+	joinAttemptsMu.Lock()
+	defer joinAttemptsMu.Unlock()
+	j, ok := joinAttempts[ipid]
+	if !ok {
+		return 0
+	}
+	wait := time.Until(j.since.Add(joinCooldown))
+	if wait <= 0 {
+		delete(joinAttempts, ipid)
+		return 0
+	} else if j.count < maxJoinAttempts {
+		return 0
+	}
+	return wait
+}
+
+// failJoinAttempt records an incorrect area password from an IPID.
+func failJoinAttempt(ipid string) {
+	joinAttemptsMu.Lock()
+	defer joinAttemptsMu.Unlock()
+	for k, j := range joinAttempts {
+		if time.Since(j.since) >= joinCooldown {
+			delete(joinAttempts, k)
+		}
+	}
+	j, ok := joinAttempts[ipid]
+	if !ok {
+		j.since = time.Now()
+	}
+	j.count++
+	joinAttempts[ipid] = j
+}
+
 // getParrotMsg returns a random string from the server's parrot list.
 func getParrotMsg() string {
-	gen := rand.New(rand.NewSource(time.Now().Unix()))
-	return parrot[gen.Intn(len(parrot))]
+	return parrot[dice.Intn(len(parrot))]
 }
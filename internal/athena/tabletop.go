@@ -0,0 +1,120 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package athena
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Handles /deck
+func cmdDeck(client *Client, args []string, usage string) {
+	a := client.Area()
+	d := a.Deck()
+	if len(args) == 0 || args[0] == "show" {
+		left, size := d.Remaining()
+		client.SendServerMessage(fmt.Sprintf("The deck has %v of %v cards left.", left, size))
+		return
+	}
+	switch args[0] {
+	case "draw":
+		flags := flag.NewFlagSet("", 0)
+		flags.SetOutput(io.Discard)
+		private := flags.Bool("p", false, "")
+		flags.Parse(args[1:])
+		n := 1
+		if flags.NArg() > 0 {
+			var err error
+			n, err = strconv.Atoi(flags.Arg(0))
+			if err != nil || n < 1 {
+				client.SendServerMessage("Invalid number of cards.")
+				return
+			}
+		}
+		cards := d.Draw(n)
+		if len(cards) == 0 {
+			client.SendServerMessage("The deck is empty.")
+			return
+		}
+		left, _ := d.Remaining()
+		drawn := strings.Join(cards, ", ")
+		if *private {
+			client.SendServerMessage(fmt.Sprintf("You drew %v. (%v cards left)", drawn, left))
+			sendAreaServerMessage(a, fmt.Sprintf("%v privately drew %v cards. (%v cards left)", client.OOCName(), len(cards), left))
+		} else {
+			sendAreaServerMessage(a, fmt.Sprintf("%v drew %v. (%v cards left)", client.OOCName(), drawn, left))
+		}
+		addToBuffer(client, "CMD", fmt.Sprintf("Drew %v.", drawn), false)
+	case "shuffle", "reset":
+		if !client.HasCMPermission() {
+			client.SendServerMessage("You do not have permission to use that command.")
+			return
+		}
+		if args[0] == "reset" {
+			d.Reset()
+			sendAreaServerMessage(a, fmt.Sprintf("%v returned all cards to the deck and shuffled it.", client.OOCName()))
+			addToBuffer(client, "CMD", "Reset the deck.", false)
+		} else {
+			d.Shuffle()
+			sendAreaServerMessage(a, fmt.Sprintf("%v shuffled the deck.", client.OOCName()))
+			addToBuffer(client, "CMD", "Shuffled the deck.", false)
+		}
+	default:
+		client.SendServerMessage("Invalid command.\n" + usage)
+	}
+}
+
+// Handles /table
+func cmdTable(client *Client, args []string, _ string) {
+	flags := flag.NewFlagSet("", 0)
+	flags.SetOutput(io.Discard)
+	private := flags.Bool("p", false, "")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		if len(tables) == 0 {
+			client.SendServerMessage("This server has no random tables.")
+			return
+		}
+		out := "\nRandom Tables\n----------"
+		for _, t := range tables {
+			out += "\n" + t.Name
+			if t.Description != "" {
+				out += ": " + t.Description
+			}
+		}
+		client.SendServerMessage(out)
+		return
+	}
+	name := strings.Join(flags.Args(), " ")
+	for _, t := range tables {
+		if !strings.EqualFold(t.Name, name) {
+			continue
+		}
+		result := t.Roll()
+		if *private {
+			client.SendServerMessage(fmt.Sprintf("You privately rolled on %v: %v", t.Name, result))
+		} else {
+			sendAreaServerMessage(client.Area(), fmt.Sprintf("%v rolled on %v: %v", client.OOCName(), t.Name, result))
+		}
+		addToBuffer(client, "CMD", fmt.Sprintf("Rolled on table %v: %v", t.Name, result), false)
+		return
+	}
+	client.SendServerMessage("No table with that name exists.")
+}
@@ -0,0 +1,123 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package athena
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MangosArentLiterature/Athena/internal/sliceutil"
+	"github.com/xhit/go-str2duration/v2"
+)
+
+// Handles /turns
+func cmdTurns(client *Client, args []string, usage string) {
+	a := client.Area()
+	t := a.Turns()
+	if len(args) == 0 || args[0] == "show" {
+		order := t.Order()
+		if len(order) == 0 {
+			client.SendServerMessage("The turn order is empty.")
+			return
+		}
+		current, _ := t.Current()
+		out := "\nTurn Order\n----------"
+		for i, uid := range order {
+			out += fmt.Sprintf("\n%v. %v", i+1, turnName(uid))
+			if uid == current {
+				out += " (current turn)"
+			}
+		}
+		if t.Strict() {
+			out += "\nStrict mode is on. Only the current turn holder can speak IC."
+		}
+		if t.Timeout() > 0 {
+			out += fmt.Sprintf("\nIdle players are skipped after %v.", t.Timeout())
+		}
+		client.SendServerMessage(out)
+		return
+	}
+	if current, _ := t.Current(); args[0] == "next" && current == client.Uid() {
+		advanceTurn(a)
+		return
+	}
+	if !client.HasCMPermission() {
+		client.SendServerMessage("You do not have permission to use that command.")
+		return
+	}
+	switch args[0] {
+	case "add", "remove":
+		if len(args) < 2 {
+			client.SendServerMessage("Not enough arguments.\n" + usage)
+			return
+		}
+		var report []string
+		for _, c := range getUidList(strings.Split(strings.Join(args[1:], ","), ",")) {
+			if args[0] == "add" {
+				if c.Area() != a || !t.Add(c.Uid()) {
+					continue
+				}
+			} else {
+				if !sliceutil.ContainsInt(t.Order(), c.Uid()) {
+					continue
+				}
+				if t.Remove(c.Uid()) {
+					advanceTurn(a)
+				}
+			}
+			report = append(report, strconv.Itoa(c.Uid()))
+		}
+		verb := "Added"
+		if args[0] == "remove" {
+			verb = "Removed"
+		}
+		client.SendServerMessage(fmt.Sprintf("%v %v users.", verb, len(report)))
+		addToBuffer(client, "CMD", fmt.Sprintf("%v %v in the turn order.", verb, strings.Join(report, ", ")), false)
+	case "next":
+		advanceTurn(a)
+		addToBuffer(client, "CMD", "Passed the turn.", false)
+	case "strict":
+		if len(args) < 2 || (args[1] != "on" && args[1] != "off") {
+			client.SendServerMessage("Invalid command.\n" + usage)
+			return
+		}
+		t.SetStrict(args[1] == "on")
+		sendAreaServerMessage(a, fmt.Sprintf("%v turned strict turn order %v.", client.OOCName(), args[1]))
+		addToBuffer(client, "CMD", fmt.Sprintf("Turned strict turn order %v.", args[1]), false)
+	case "timeout":
+		if len(args) < 2 {
+			client.SendServerMessage("Not enough arguments.\n" + usage)
+			return
+		}
+		d, err := str2duration.ParseDuration(args[1])
+		if err != nil || d < 0 || d > 24*time.Hour {
+			client.SendServerMessage("Invalid duration.")
+			return
+		}
+		t.SetTimeout(d)
+		client.SendServerMessage(fmt.Sprintf("Set the turn timeout to %v.", d))
+		addToBuffer(client, "CMD", fmt.Sprintf("Set the turn timeout to %v.", d), false)
+	case "clear":
+		t.Clear()
+		sendAreaServerMessage(a, fmt.Sprintf("%v cleared the turn order.", client.OOCName()))
+		addToBuffer(client, "CMD", "Cleared the turn order.", false)
+	default:
+		client.SendServerMessage("Invalid command.\n" + usage)
+	}
+}
@@ -0,0 +1,304 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package athena
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MangosArentLiterature/Athena/internal/permissions"
+	"github.com/MangosArentLiterature/Athena/internal/vote"
+	"github.com/xhit/go-str2duration/v2"
+)
+
+// Handles /jury
+func cmdJury(client *Client, args []string, usage string) {
+	if len(args) == 0 || args[0] == "show" {
+		v := client.Area().Jury()
+		if v == nil {
+			client.SendServerMessage("No jury vote has been held in this area.")
+			return
+		}
+		out := voteResults("Jury Vote", v)
+		if v.Open() {
+			out += fmt.Sprintf("\n%v of %v jurors have voted.", v.Count(), len(client.Area().Jurors()))
+		}
+		client.SendServerMessage(out)
+		return
+	}
+	if !client.HasCMPermission() {
+		client.SendServerMessage("You do not have permission to use that command.")
+		return
+	}
+	switch args[0] {
+	case "start":
+		flags := flag.NewFlagSet("", 0)
+		flags.SetOutput(io.Discard)
+		anonymous := flags.Bool("a", false, "")
+		timeout := flags.String("t", "", "")
+		flags.Parse(args[1:])
+		parts := strings.Split(strings.Join(flags.Args(), " "), "|")
+		question := strings.TrimSpace(parts[0])
+		if question == "" {
+			client.SendServerMessage("Not enough arguments.\n" + usage)
+			return
+		}
+		var options []string
+		for _, o := range parts[1:] {
+			if o = strings.TrimSpace(o); o != "" {
+				options = append(options, o)
+			}
+		}
+		if len(options) == 0 {
+			options = []string{"Guilty", "Not guilty"}
+		} else if len(options) < 2 {
+			client.SendServerMessage("A vote needs at least 2 options.")
+			return
+		}
+		d := juryTimeout
+		if *timeout != "" {
+			var err error
+			d, err = str2duration.ParseDuration(*timeout)
+			if err != nil || d <= 0 || d > 24*time.Hour {
+				client.SendServerMessage("Invalid duration.")
+				return
+			}
+		}
+		if len(client.Area().Jurors()) == 0 {
+			client.SendServerMessage("This area has no jurors. Invite jurors with /jury invite, or assign users to the jur position.")
+			return
+		}
+		if old := client.Area().Jury(); old != nil && old.Open() {
+			client.SendServerMessage("A jury vote is already in progress.")
+			return
+		}
+		a := client.Area()
+		v := vote.New(question, options, *anonymous)
+		a.SetJury(v)
+		v.CloseAfter(d, func() { closeJury(a, v) })
+		mode := "openly"
+		if *anonymous {
+			mode = "anonymously"
+		}
+		out := fmt.Sprintf("%v started a jury vote: %v\nJurors vote %v with /vote <option> within %v.", client.OOCName(), question, mode, d)
+		for i, o := range options {
+			out += fmt.Sprintf("\n%v. %v", i+1, o)
+		}
+		sendAreaServerMessage(a, out)
+		addToBuffer(client, "JURY", fmt.Sprintf("Started a jury vote: %v", question), false)
+	case "invite", "uninvite":
+		if len(args) < 2 {
+			client.SendServerMessage("Not enough arguments.\n" + usage)
+			return
+		}
+		var count int
+		var report string
+		for _, c := range getUidList(strings.Split(args[1], ",")) {
+			if c.Area() != client.Area() {
+				continue
+			}
+			if args[0] == "invite" {
+				if !client.Area().AddJuror(c.Uid()) {
+					continue
+				}
+				c.SendServerMessage(fmt.Sprintf("You were made a juror in %v.", client.Area().Name()))
+			} else {
+				if !client.Area().RemoveJuror(c.Uid()) {
+					continue
+				}
+				c.SendServerMessage(fmt.Sprintf("You are no longer a juror in %v.", client.Area().Name()))
+			}
+			count++
+			report += fmt.Sprintf("%v, ", c.Uid())
+		}
+		report = strings.TrimSuffix(report, ", ")
+		verb := "Invited"
+		if args[0] == "uninvite" {
+			verb = "Uninvited"
+		}
+		client.SendServerMessage(fmt.Sprintf("%v %v jurors.", verb, count))
+		addToBuffer(client, "CMD", fmt.Sprintf("%v %v as jurors.", verb, report), false)
+	case "close":
+		v := client.Area().Jury()
+		if v == nil || !v.Close() {
+			client.SendServerMessage("There is no jury vote in progress.")
+			return
+		}
+		closeJury(client.Area(), v)
+	default:
+		client.SendServerMessage("Invalid command.\n" + usage)
+	}
+}
+
+// Handles /vote
+func cmdVote(client *Client, args []string, _ string) {
+	a := client.Area()
+	v := a.Jury()
+	if v == nil || !v.Open() {
+		client.SendServerMessage("There is no jury vote in progress.")
+		return
+	}
+	if !a.IsJuror(client.Uid()) {
+		client.SendServerMessage("You are not a juror in this area.")
+		return
+	}
+	option := v.Option(strings.Join(args, " "))
+	if option == -1 {
+		client.SendServerMessage("Invalid option.")
+		return
+	}
+	changed, err := v.Cast(strconv.Itoa(client.Uid()), client.CurrentCharacter(), option)
+	if err != nil {
+		client.SendServerMessage("There is no jury vote in progress.")
+		return
+	}
+	count, jurors := v.Count(), len(a.Jurors())
+	verb := "voted for"
+	if changed {
+		verb = "changed their vote to"
+	}
+	if v.Anonymous() {
+		client.SendServerMessage(fmt.Sprintf("You %v %v.", verb, v.Options()[option]))
+		sendAreaServerMessage(a, fmt.Sprintf("A juror has voted. (%v/%v)", count, jurors))
+	} else {
+		sendAreaServerMessage(a, fmt.Sprintf("%v %v %v. (%v/%v)", client.CurrentCharacter(), verb, v.Options()[option], count, jurors))
+	}
+	addToBuffer(client, "JURY", "Cast a jury vote.", false)
+	if count >= jurors && v.Close() {
+		closeJury(a, v)
+	}
+}
+
+// Handles /poll
+func cmdPoll(client *Client, args []string, usage string) {
+	flags := flag.NewFlagSet("", 0)
+	flags.SetOutput(io.Discard)
+	global := flags.Bool("g", false, "")
+	flags.Parse(args)
+	args = flags.Args()
+
+	title := "Poll"
+	var get func() *vote.Vote
+	var set func(v *vote.Vote)
+	var announce func(msg string)
+	var canManage bool
+	if *global {
+		title = "Global Poll"
+		get = func() *vote.Vote {
+			pollMu.Lock()
+			defer pollMu.Unlock()
+			return globalPoll
+		}
+		set = func(v *vote.Vote) {
+			pollMu.Lock()
+			globalPoll = v
+			pollMu.Unlock()
+		}
+		announce = func(msg string) { writeToAll("CT", encode(config.Name), encode(msg), "1") }
+		canManage = client.HasGlobalPermission(permissions.PermissionField["MOD_SPEAK"])
+	} else {
+		a := client.Area()
+		get = a.Poll
+		set = a.SetPoll
+		announce = func(msg string) { sendAreaServerMessage(a, msg) }
+		canManage = client.HasCMPermission()
+	}
+
+	if len(args) == 0 || args[0] == "results" {
+		v := get()
+		if v == nil {
+			client.SendServerMessage("No poll has been held.")
+			return
+		}
+		client.SendServerMessage(voteResults(title, v) + fmt.Sprintf("\n%v votes cast.", v.Count()))
+		return
+	}
+	switch args[0] {
+	case "create":
+		if !canManage {
+			client.SendServerMessage("You do not have permission to use that command.")
+			return
+		}
+		parts := strings.Split(strings.Join(args[1:], " "), "|")
+		question := strings.TrimSpace(parts[0])
+		var options []string
+		for _, o := range parts[1:] {
+			if o = strings.TrimSpace(o); o != "" {
+				options = append(options, o)
+			}
+		}
+		if question == "" || len(options) < 2 {
+			client.SendServerMessage("A poll needs a question and at least 2 options.\n" + usage)
+			return
+		}
+		if old := get(); old != nil && old.Open() {
+			client.SendServerMessage("A poll is already in progress.")
+			return
+		}
+		set(vote.New(question, options, true))
+		out := fmt.Sprintf("%v started a poll: %v\nVote with /poll ", client.OOCName(), question)
+		if *global {
+			out += "-g "
+		}
+		out += "vote <option>."
+		for i, o := range options {
+			out += fmt.Sprintf("\n%v. %v", i+1, o)
+		}
+		announce(out)
+		addToBuffer(client, "POLL", fmt.Sprintf("Started a %v: %v", strings.ToLower(title), question), *global)
+	case "vote":
+		v := get()
+		if v == nil || !v.Open() {
+			client.SendServerMessage("There is no poll in progress.")
+			return
+		}
+		option := v.Option(strings.Join(args[1:], " "))
+		if option == -1 {
+			client.SendServerMessage("Invalid option.")
+			return
+		}
+		// Votes are counted per IPID to prevent multiclients from voting more than once.
+		changed, err := v.Cast(client.Ipid(), client.OOCName(), option)
+		if err != nil {
+			client.SendServerMessage("There is no poll in progress.")
+			return
+		}
+		if changed {
+			client.SendServerMessage(fmt.Sprintf("You changed your vote to %v.", v.Options()[option]))
+		} else {
+			client.SendServerMessage(fmt.Sprintf("You voted for %v.", v.Options()[option]))
+		}
+	case "close":
+		if !canManage {
+			client.SendServerMessage("You do not have permission to use that command.")
+			return
+		}
+		v := get()
+		if v == nil || !v.Close() {
+			client.SendServerMessage("There is no poll in progress.")
+			return
+		}
+		announce(voteResults(title, v))
+		addToBuffer(client, "POLL", fmt.Sprintf("Closed the %v \"%v\". %v.", strings.ToLower(title), v.Question(), voteTally(v)), *global)
+	default:
+		client.SendServerMessage("Invalid command.\n" + usage)
+	}
+}
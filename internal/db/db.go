@@ -35,6 +35,24 @@ type BanInfo struct {
 	Moderator string
 }
 
+type CaseInfo struct {
+	Id           int
+	Title        string
+	Area         string
+	Host         string
+	Start        int64
+	End          int64
+	Verdict      string
+	Participants string
+	Transcript   string
+}
+
+// AreaRef identifies an area by it's hub and name, which unlike it's position stay the same when areas are added or reordered.
+type AreaRef struct {
+	Hub  string
+	Area string
+}
+
 type BanLookup int
 
 const (
@@ -48,7 +66,7 @@ var db *sql.DB
 
 // Database version.
 // This should be incremented whenever changes are made to the DB that require existing databases to upgrade.
-const ver = 1
+const ver = 2
 
 // Opens the server's database connection.
 func Open() error {
@@ -74,6 +92,14 @@ func Open() error {
 	if err != nil {
 		return err
 	}
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS AREA_PERMISSIONS(USERNAME TEXT, HUB TEXT, AREA TEXT, PERMISSIONS TEXT, PRIMARY KEY(USERNAME, HUB, AREA))")
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS CASES(ID INTEGER PRIMARY KEY, TITLE TEXT, AREA TEXT, HOST TEXT, START INTEGER, END INTEGER, VERDICT TEXT, PARTICIPANTS TEXT, TRANSCRIPT TEXT)")
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -85,6 +111,17 @@ func upgradeDB(v int) error {
 		if err != nil {
 			return err
 		}
+		fallthrough
+	case 1:
+		// Version 1 stored area permissions by area position, which can't be mapped to an area reliably.
+		_, err := db.Exec("DROP TABLE IF EXISTS AREA_PERMISSIONS")
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec("PRAGMA user_version = " + "2")
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -118,6 +155,10 @@ func RemoveUser(username string) error {
 	if err != nil {
 		return err
 	}
+	_, err = db.Exec("DELETE FROM AREA_PERMISSIONS WHERE USERNAME = ?", username)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -146,6 +187,43 @@ func ChangePermissions(username string, permissions uint64) error {
 	return nil
 }
 
+// GetAreaPermissions returns a user's area-scoped permissions, indexed by area.
+func GetAreaPermissions(username string) (map[AreaRef]uint64, error) {
+	result, err := db.Query("SELECT HUB, AREA, PERMISSIONS FROM AREA_PERMISSIONS WHERE USERNAME = ?", username)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	perms := make(map[AreaRef]uint64)
+	for result.Next() {
+		var (
+			area   AreaRef
+			rperms string
+		)
+		result.Scan(&area.Hub, &area.Area, &rperms)
+		p, err := strconv.ParseUint(rperms, 10, 64)
+		if err != nil {
+			continue
+		}
+		perms[area] = p
+	}
+	return perms, nil
+}
+
+// SetAreaPermissions sets a user's permissions within an area, removing the entry if there are none.
+func SetAreaPermissions(username string, area AreaRef, permissions uint64) error {
+	var err error
+	if permissions == 0 {
+		_, err = db.Exec("DELETE FROM AREA_PERMISSIONS WHERE USERNAME = ? AND HUB = ? AND AREA = ?", username, area.Hub, area.Area)
+	} else {
+		_, err = db.Exec("INSERT OR REPLACE INTO AREA_PERMISSIONS VALUES(?, ?, ?, ?)", username, area.Hub, area.Area, strconv.FormatUint(permissions, 10))
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // AddBan adds a new ban to the database.
 func AddBan(ipid string, hdid string, time int64, duration int64, reason string, moderator string) (int, error) {
 	result, err := db.Exec("INSERT INTO BANS VALUES(NULL, ?, ?, ?, ?, ?, ?)", ipid, hdid, time, duration, reason, moderator)
@@ -254,6 +332,41 @@ func UpdateBan(id int, reason string) error {
 	return nil
 }
 
+// AddCase adds a finished case to the database.
+func AddCase(c CaseInfo) (int, error) {
+	result, err := db.Exec("INSERT INTO CASES VALUES(NULL, ?, ?, ?, ?, ?, ?, ?, ?)", c.Title, c.Area, c.Host, c.Start, c.End, c.Verdict, c.Participants, c.Transcript)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// GetRecentCases returns the given number of most recently finished cases.
+func GetRecentCases(limit int) ([]CaseInfo, error) {
+	result, err := db.Query("SELECT * FROM CASES ORDER BY END DESC LIMIT ?", limit)
+	if err != nil {
+		return []CaseInfo{}, err
+	}
+	defer result.Close()
+	var cases []CaseInfo
+	for result.Next() {
+		var c CaseInfo
+		err := result.Scan(&c.Id, &c.Title, &c.Area, &c.Host, &c.Start, &c.End, &c.Verdict, &c.Participants, &c.Transcript)
+		if err != nil {
+			return []CaseInfo{}, err
+		}
+		cases = append(cases, c)
+	}
+	if err := result.Err(); err != nil {
+		return []CaseInfo{}, err
+	}
+	return cases, nil
+}
+
 // Closes the server's database connection.
 func Close() {
 	db.Close()
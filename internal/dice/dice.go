@@ -0,0 +1,212 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package dice
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxExplosions limits how many extra dice a single exploding term can roll.
+const maxExplosions = 100
+
+var (
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rngMu sync.Mutex
+
+	termRegex = regexp.MustCompile(`^(\d*)d(\d+|%)(!)?(?:k([hl])?(\d+))?$`)
+)
+
+// Intn returns a random number in [0,n) from the shared random number generator.
+func Intn(n int) int {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return rng.Intn(n)
+}
+
+// Term is a single term of a dice expression, such as "4d6kh3" or "3".
+type Term struct {
+	Notation string
+	Negative bool
+	Rolls    []int
+	Dropped  []bool
+	Total    int
+}
+
+// Result is the result of a rolled dice expression.
+type Result struct {
+	Terms []Term
+	Total int
+}
+
+// Roll rolls a dice expression, such as "2d6+3", "4d6kh3", "d%" or "3d6!".
+// The expression may roll at most maxDice dice, not counting explosions, with at most maxSides sides each.
+func Roll(expr string, maxDice int, maxSides int) (Result, error) {
+	expr = strings.ToLower(strings.ReplaceAll(expr, " ", ""))
+	if expr == "" {
+		return Result{}, fmt.Errorf("empty expression")
+	}
+	var res Result
+	var dice int
+	negative := false
+	start := 0
+	for i := 0; i <= len(expr); i++ {
+		if i < len(expr) && expr[i] != '+' && expr[i] != '-' {
+			continue
+		}
+		if i == start {
+			if i == 0 && i < len(expr) && expr[i] == '-' {
+				negative = true
+				start = i + 1
+				continue
+			}
+			return Result{}, fmt.Errorf("missing term")
+		}
+		t, n, err := rollTerm(expr[start:i], maxDice-dice, maxSides)
+		if err != nil {
+			return Result{}, err
+		}
+		dice += n
+		t.Negative = negative
+		if negative {
+			res.Total -= t.Total
+		} else {
+			res.Total += t.Total
+		}
+		res.Terms = append(res.Terms, t)
+		if i < len(expr) {
+			negative = expr[i] == '-'
+		}
+		start = i + 1
+	}
+	return res, nil
+}
+
+// rollTerm rolls a single term of at most maxDice dice, returning it and the number of dice it rolled before explosions.
+func rollTerm(s string, maxDice int, maxSides int) (Term, int, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return Term{Notation: s, Total: n}, 0, nil
+	}
+	m := termRegex.FindStringSubmatch(s)
+	if m == nil {
+		return Term{}, 0, fmt.Errorf("invalid term %v", s)
+	}
+	count := 1
+	var err error
+	if m[1] != "" {
+		if count, err = strconv.Atoi(m[1]); err != nil {
+			return Term{}, 0, fmt.Errorf("invalid number of dice in %v", s)
+		}
+	}
+	sides := 100
+	if m[2] != "%" {
+		if sides, err = strconv.Atoi(m[2]); err != nil {
+			return Term{}, 0, fmt.Errorf("invalid number of sides in %v", s)
+		}
+	}
+	if count < 1 || sides < 1 || sides > maxSides {
+		return Term{}, 0, fmt.Errorf("invalid number of dice or sides in %v", s)
+	}
+	if count > maxDice {
+		return Term{}, 0, fmt.Errorf("too many dice")
+	}
+	explode := m[3] != ""
+	if explode && sides == 1 {
+		return Term{}, 0, fmt.Errorf("cannot explode a one sided die")
+	}
+
+	t := Term{Notation: s}
+	rngMu.Lock()
+	explosions := 0
+	for i := 0; i < count; i++ {
+		r := rng.Intn(sides) + 1
+		t.Rolls = append(t.Rolls, r)
+		if explode && r == sides && explosions < maxExplosions {
+			explosions++
+			i--
+		}
+	}
+	rngMu.Unlock()
+
+	t.Dropped = make([]bool, len(t.Rolls))
+	if m[5] != "" {
+		keep, err := strconv.Atoi(m[5])
+		if err != nil || keep < 1 {
+			return Term{}, 0, fmt.Errorf("invalid number of dice to keep in %v", s)
+		}
+		order := make([]int, len(t.Rolls))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			if m[4] == "l" {
+				return t.Rolls[order[a]] < t.Rolls[order[b]]
+			}
+			return t.Rolls[order[a]] > t.Rolls[order[b]]
+		})
+		if keep < len(order) {
+			for _, i := range order[keep:] {
+				t.Dropped[i] = true
+			}
+		}
+	}
+	for i, r := range t.Rolls {
+		if !t.Dropped[i] {
+			t.Total += r
+		}
+	}
+	return t, count, nil
+}
+
+// String returns the term's breakdown, with dropped dice in parentheses.
+func (t Term) String() string {
+	if t.Rolls == nil {
+		return t.Notation
+	}
+	rolls := make([]string, len(t.Rolls))
+	for i, r := range t.Rolls {
+		if t.Dropped[i] {
+			rolls[i] = fmt.Sprintf("(%v)", r)
+		} else {
+			rolls[i] = strconv.Itoa(r)
+		}
+	}
+	return fmt.Sprintf("%v [%v]", t.Notation, strings.Join(rolls, ", "))
+}
+
+// String returns the result's per-term breakdown and total.
+func (r Result) String() string {
+	var s string
+	for i, t := range r.Terms {
+		switch {
+		case t.Negative && i == 0:
+			s += "-"
+		case t.Negative:
+			s += " - "
+		case i > 0:
+			s += " + "
+		}
+		s += t.String()
+	}
+	return fmt.Sprintf("%v = %v", s, r.Total)
+}
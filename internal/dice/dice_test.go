@@ -0,0 +1,92 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package dice
+
+import "testing"
+
+func TestRoll(t *testing.T) {
+	r, err := Roll("2d6+3", 100, 100)
+	if err != nil {
+		t.Fatalf("unexpected error for 2d6+3: %v", err)
+	}
+	if len(r.Terms) != 2 || r.Total != r.Terms[0].Total+3 {
+		t.Errorf("unexpected result for 2d6+3, got %v", r)
+	}
+
+	// Keep highest should drop the lowest die.
+	r, _ = Roll("4d6kh3", 100, 100)
+	min, dropped := 7, 0
+	for i, v := range r.Terms[0].Rolls {
+		if v < min {
+			min = v
+		}
+		if r.Terms[0].Dropped[i] {
+			dropped++
+		}
+	}
+	if dropped != 1 {
+		t.Errorf("unexpected number of dropped dice, got %d, want %d", dropped, 1)
+	}
+	sum := 0
+	for _, v := range r.Terms[0].Rolls {
+		sum += v
+	}
+	if r.Total != sum-min {
+		t.Errorf("unexpected total for 4d6kh3, got %d, want %d", r.Total, sum-min)
+	}
+
+	// Exploding dice roll an extra die for every maximum roll.
+	r, _ = Roll("10d2!", 100, 100)
+	extra := 0
+	for _, v := range r.Terms[0].Rolls {
+		if v == 2 {
+			extra++
+		}
+	}
+	if len(r.Terms[0].Rolls) != 10+extra {
+		t.Errorf("unexpected number of exploded dice, got %d, want %d", len(r.Terms[0].Rolls), 10+extra)
+	}
+
+	r, _ = Roll("d%-5", 100, 100)
+	if r.Total < -4 || r.Total > 95 {
+		t.Errorf("unexpected total for d%%-5, got %d", r.Total)
+	}
+
+	for _, expr := range []string{"", "d", "2d6+", "abc", "200d6", "60d6+60d6", "99999999999999999999d6", "1d1000", "3d1!", "++2"} {
+		if _, err := Roll(expr, 100, 100); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	table := Table{Name: "weather", Entries: []TableEntry{{Result: "Sunny", Weight: 3}, {Result: "Rain"}, {Result: "Snow", Weight: -1}}}
+	if table.Validate() == nil {
+		t.Errorf("expected error for negative weight")
+	}
+	table.Entries = table.Entries[:2]
+	if err := table.Validate(); err != nil {
+		t.Fatalf("unexpected error for Validate(): %v", err)
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[table.Roll()]++
+	}
+	if counts["Sunny"]+counts["Rain"] != 1000 || counts["Sunny"] < counts["Rain"] {
+		t.Errorf("unexpected distribution of results, got %v", counts)
+	}
+}
@@ -0,0 +1,72 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package dice
+
+import "fmt"
+
+// TableEntry is a possible result of a random table.
+type TableEntry struct {
+	Result string `toml:"result"`
+	Weight int    `toml:"weight"`
+}
+
+// Table is a weighted random table.
+type Table struct {
+	Name        string       `toml:"name"`
+	Description string       `toml:"description"`
+	Entries     []TableEntry `toml:"entries"`
+}
+
+// Validate returns an error if the table has no entries, or an entry has an invalid weight.
+func (t Table) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("table has no name")
+	}
+	if len(t.Entries) == 0 {
+		return fmt.Errorf("table %v has no entries", t.Name)
+	}
+	for _, e := range t.Entries {
+		if e.Weight < 0 {
+			return fmt.Errorf("table %v has an entry with a negative weight", t.Name)
+		}
+	}
+	return nil
+}
+
+// Roll returns a random result from the table. Entries without a weight have a weight of 1.
+func (t Table) Roll() string {
+	total := 0
+	for _, e := range t.Entries {
+		total += weight(e)
+	}
+	n := Intn(total)
+	for _, e := range t.Entries {
+		if n < weight(e) {
+			return e.Result
+		}
+		n -= weight(e)
+	}
+	return t.Entries[len(t.Entries)-1].Result
+}
+
+// weight returns an entry's weight, defaulting to 1.
+func weight(e TableEntry) int {
+	if e.Weight == 0 {
+		return 1
+	}
+	return e.Weight
+}
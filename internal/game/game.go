@@ -0,0 +1,520 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Teams a role can belong to.
+const (
+	TeamVillage = "village"
+	TeamWolves  = "wolves"
+)
+
+// Night actions a role can have.
+const (
+	ActionKill        = "kill"
+	ActionInvestigate = "investigate"
+	ActionProtect     = "protect"
+)
+
+var (
+	ErrNotRunning    = errors.New("the game is not running")
+	ErrStarted       = errors.New("the game has already started")
+	ErrNotPlaying    = errors.New("you are not playing")
+	ErrAlreadyJoined = errors.New("you have already joined")
+	ErrDead          = errors.New("you are dead")
+	ErrWrongPhase    = errors.New("you cannot do that now")
+	ErrNoAction      = errors.New("your role has no night action")
+	ErrInvalidTarget = errors.New("invalid target")
+)
+
+// Role is a role players can be assigned.
+type Role struct {
+	Name        string `toml:"name"`
+	Team        string `toml:"team"`
+	Action      string `toml:"action"`
+	Count       int    `toml:"count"`
+	Description string `toml:"description"`
+}
+
+// Config is a game's configuration, read from games.toml.
+type Config struct {
+	DayLength   string `toml:"day_length"`
+	NightLength string `toml:"night_length"`
+	MinPlayers  int    `toml:"min_players"`
+	Roles       []Role `toml:"Role"`
+}
+
+// Villager is the role given to players who are not assigned one of the configured roles.
+var Villager = Role{Name: "Villager", Team: TeamVillage, Description: "Find the werewolves and lynch them during the day."}
+
+// Validate returns an error if the config's roles are invalid.
+func (c Config) Validate() error {
+	wolves := false
+	for _, r := range c.Roles {
+		if r.Name == "" {
+			return fmt.Errorf("role has no name")
+		}
+		if r.Team != TeamVillage && r.Team != TeamWolves {
+			return fmt.Errorf("role %v has invalid team %v", r.Name, r.Team)
+		}
+		switch r.Action {
+		case "", ActionKill, ActionInvestigate, ActionProtect:
+		default:
+			return fmt.Errorf("role %v has invalid action %v", r.Name, r.Action)
+		}
+		if r.Count < 1 {
+			return fmt.Errorf("role %v has invalid count %v", r.Name, r.Count)
+		}
+		if r.Team == TeamWolves {
+			wolves = true
+		}
+	}
+	if !wolves {
+		return fmt.Errorf("no roles on the %v team", TeamWolves)
+	}
+	return nil
+}
+
+type Phase int
+
+const (
+	PhaseLobby Phase = iota
+	PhaseNight
+	PhaseDay
+	PhaseOver
+)
+
+// String returns the string representation of the phase.
+func (p Phase) String() string {
+	switch p {
+	case PhaseLobby:
+		return "Lobby"
+	case PhaseNight:
+		return "Night"
+	case PhaseDay:
+		return "Day"
+	case PhaseOver:
+		return "Over"
+	}
+	return ""
+}
+
+// Player is a player in a game.
+type Player struct {
+	Uid   int
+	Name  string
+	Role  Role
+	Alive bool
+}
+
+// NightResult is the outcome of a night.
+type NightResult struct {
+	Killed         *Player
+	Saved          bool
+	Investigations map[int]Player
+}
+
+// DayResult is the outcome of a day's lynch vote.
+type DayResult struct {
+	Lynched *Player
+}
+
+// Game is a game of werewolf.
+type Game struct {
+	mu      sync.Mutex
+	cfg     Config
+	phase   Phase
+	day     int
+	players []*Player
+	actions map[int]int
+	votes   map[int]int
+	winner  string
+	gen     int
+	timer   *time.Timer
+}
+
+// New returns a new game in it's lobby phase.
+func New(cfg Config) *Game {
+	return &Game{cfg: cfg}
+}
+
+// Config returns the game's config.
+func (g *Game) Config() Config {
+	return g.cfg
+}
+
+// Phase returns the game's current phase.
+func (g *Game) Phase() Phase {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.phase
+}
+
+// Day returns the current day number.
+func (g *Game) Day() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.day
+}
+
+// Winner returns the winning team, or an empty string if the game has not been won.
+func (g *Game) Winner() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.winner
+}
+
+// Players returns the game's players.
+func (g *Game) Players() []Player {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	l := make([]Player, len(g.players))
+	for i, p := range g.players {
+		l[i] = *p
+	}
+	return l
+}
+
+// Player returns a player by UID.
+func (g *Game) Player(uid int) (Player, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p := g.player(uid); p != nil {
+		return *p, true
+	}
+	return Player{}, false
+}
+
+// player returns a player by UID, or nil. The caller must hold the lock.
+func (g *Game) player(uid int) *Player {
+	for _, p := range g.players {
+		if p.Uid == uid {
+			return p
+		}
+	}
+	return nil
+}
+
+// Join adds a player to the game's lobby.
+func (g *Game) Join(uid int, name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.phase != PhaseLobby {
+		return ErrStarted
+	}
+	if g.player(uid) != nil {
+		return ErrAlreadyJoined
+	}
+	g.players = append(g.players, &Player{Uid: uid, Name: name, Alive: true})
+	return nil
+}
+
+// Leave removes a player from the game. Once the game has started, the player is killed instead.
+// It returns the player, and false if they were not playing.
+func (g *Game) Leave(uid int) (Player, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, p := range g.players {
+		if p.Uid != uid {
+			continue
+		}
+		if g.phase == PhaseLobby {
+			g.players = append(g.players[:i], g.players[i+1:]...)
+			return *p, true
+		}
+		wasAlive := p.Alive
+		p.Alive = false
+		delete(g.actions, uid)
+		delete(g.votes, uid)
+		if wasAlive && g.phase != PhaseOver {
+			g.checkWin()
+		}
+		return *p, wasAlive
+	}
+	return Player{}, false
+}
+
+// Start assigns roles and starts the first night. intn is used to shuffle the players.
+func (g *Game) Start(intn func(int) int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.phase != PhaseLobby {
+		return ErrStarted
+	}
+	needed := 0
+	for _, r := range g.cfg.Roles {
+		needed += r.Count
+	}
+	min := g.cfg.MinPlayers
+	if needed+1 > min {
+		min = needed + 1
+	}
+	if len(g.players) < min {
+		return fmt.Errorf("at least %v players are needed", min)
+	}
+	for i := len(g.players) - 1; i > 0; i-- {
+		j := intn(i + 1)
+		g.players[i], g.players[j] = g.players[j], g.players[i]
+	}
+	i := 0
+	for _, r := range g.cfg.Roles {
+		for n := 0; n < r.Count; n++ {
+			g.players[i].Role = r
+			i++
+		}
+	}
+	for ; i < len(g.players); i++ {
+		g.players[i].Role = Villager
+	}
+	g.phase = PhaseNight
+	g.day = 1
+	g.gen++
+	g.actions = make(map[int]int)
+	return nil
+}
+
+// living returns the living player with the given UID, or an error. The caller must hold the lock.
+func (g *Game) living(uid int) (*Player, error) {
+	if g.phase == PhaseLobby || g.phase == PhaseOver {
+		return nil, ErrNotRunning
+	}
+	p := g.player(uid)
+	if p == nil {
+		return nil, ErrNotPlaying
+	}
+	if !p.Alive {
+		return nil, ErrDead
+	}
+	return p, nil
+}
+
+// Act sets a player's night action target.
+func (g *Game) Act(uid int, target int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p, err := g.living(uid)
+	if err != nil {
+		return err
+	}
+	if g.phase != PhaseNight {
+		return ErrWrongPhase
+	}
+	if p.Role.Action == "" {
+		return ErrNoAction
+	}
+	t := g.player(target)
+	if t == nil || !t.Alive || (p.Role.Action == ActionKill && t.Role.Team == TeamWolves) ||
+		(p.Role.Action == ActionInvestigate && t == p) {
+		return ErrInvalidTarget
+	}
+	g.actions[uid] = target
+	return nil
+}
+
+// ActionsDone returns whether every living player with a night action has used it.
+func (g *Game) ActionsDone() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range g.players {
+		if _, ok := g.actions[p.Uid]; p.Alive && p.Role.Action != "" && !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Vote sets a player's lynch vote, returning the number of votes against the target.
+func (g *Game) Vote(uid int, target int) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, err := g.living(uid); err != nil {
+		return 0, err
+	}
+	if g.phase != PhaseDay {
+		return 0, ErrWrongPhase
+	}
+	if t := g.player(target); t == nil || !t.Alive {
+		return 0, ErrInvalidTarget
+	}
+	g.votes[uid] = target
+	count := 0
+	for _, t := range g.votes {
+		if t == target {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Majority returns whether more than half of the living players have voted to lynch the same player.
+func (g *Game) Majority() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	alive := 0
+	for _, p := range g.players {
+		if p.Alive {
+			alive++
+		}
+	}
+	_, count, _ := g.top(g.votes)
+	return count*2 > alive
+}
+
+// top returns the most chosen target in a set of choices, it's count, and whether it was tied. The caller must hold the lock.
+func (g *Game) top(choices map[int]int) (int, int, bool) {
+	counts := make(map[int]int)
+	for _, t := range choices {
+		counts[t]++
+	}
+	target, max, tie := -1, 0, false
+	for _, p := range g.players {
+		switch c := counts[p.Uid]; {
+		case c > max:
+			target, max, tie = p.Uid, c, false
+		case c == max && c > 0:
+			tie = true
+		}
+	}
+	return target, max, tie
+}
+
+// EndNight resolves the night's actions and starts the day.
+func (g *Game) EndNight() (NightResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.phase != PhaseNight {
+		return NightResult{}, ErrWrongPhase
+	}
+	kills := make(map[int]int)
+	protected := make(map[int]bool)
+	res := NightResult{Investigations: make(map[int]Player)}
+	for uid, target := range g.actions {
+		p := g.player(uid)
+		if p == nil || !p.Alive {
+			continue
+		}
+		switch p.Role.Action {
+		case ActionKill:
+			kills[uid] = target
+		case ActionProtect:
+			protected[target] = true
+		case ActionInvestigate:
+			res.Investigations[uid] = *g.player(target)
+		}
+	}
+	// Ties between the wolves' choices are broken by join order.
+	if target, _, _ := g.top(kills); target != -1 {
+		if protected[target] {
+			res.Saved = true
+		} else if t := g.player(target); t.Alive {
+			t.Alive = false
+			killed := *t
+			res.Killed = &killed
+		}
+	}
+	g.phase = PhaseDay
+	g.gen++
+	g.votes = make(map[int]int)
+	g.checkWin()
+	return res, nil
+}
+
+// EndDay resolves the day's lynch vote and starts the next night. Nobody is lynched on a tie.
+func (g *Game) EndDay() (DayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.phase != PhaseDay {
+		return DayResult{}, ErrWrongPhase
+	}
+	var res DayResult
+	if target, _, tie := g.top(g.votes); target != -1 && !tie {
+		if t := g.player(target); t.Alive {
+			t.Alive = false
+			lynched := *t
+			res.Lynched = &lynched
+		}
+	}
+	g.phase = PhaseNight
+	g.day++
+	g.gen++
+	g.actions = make(map[int]int)
+	g.checkWin()
+	return res, nil
+}
+
+// checkWin ends the game if either team has won. The caller must hold the lock.
+func (g *Game) checkWin() {
+	wolves, village := 0, 0
+	for _, p := range g.players {
+		if !p.Alive {
+			continue
+		}
+		if p.Role.Team == TeamWolves {
+			wolves++
+		} else {
+			village++
+		}
+	}
+	switch {
+	case wolves == 0:
+		g.winner = TeamVillage
+	case wolves >= village:
+		g.winner = TeamWolves
+	default:
+		return
+	}
+	g.phase = PhaseOver
+	g.gen++
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+}
+
+// AfterPhase calls f after the given duration, unless the phase has changed by then.
+func (g *Game) AfterPhase(d time.Duration, f func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	gen := g.gen
+	g.timer = time.AfterFunc(d, func() {
+		g.mu.Lock()
+		stale := gen != g.gen
+		g.mu.Unlock()
+		if !stale {
+			f()
+		}
+	})
+}
+
+// Stop ends the game without a winner.
+func (g *Game) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.phase = PhaseOver
+	g.gen++
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+}
@@ -0,0 +1,94 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package game
+
+import "testing"
+
+func TestGame(t *testing.T) {
+	cfg := Config{
+		MinPlayers: 4,
+		Roles: []Role{
+			{Name: "Werewolf", Team: TeamWolves, Action: ActionKill, Count: 1},
+			{Name: "Doctor", Team: TeamVillage, Action: ActionProtect, Count: 1},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error for Validate(): %v", err)
+	}
+	g := New(cfg)
+	for uid := 0; uid < 4; uid++ {
+		g.Join(uid, "player")
+	}
+	if g.Join(0, "player") != ErrAlreadyJoined {
+		t.Errorf("expected ErrAlreadyJoined for duplicate join")
+	}
+
+	// With no shuffling, roles are assigned in join order.
+	if err := g.Start(func(n int) int { return n - 1 }); err != nil {
+		t.Fatalf("unexpected error for Start(): %v", err)
+	}
+	if p, _ := g.Player(0); p.Role.Name != "Werewolf" {
+		t.Fatalf("unexpected role for player 0, got %s, want %s", p.Role.Name, "Werewolf")
+	}
+	if g.Act(0, 0) != ErrInvalidTarget {
+		t.Errorf("expected ErrInvalidTarget for a werewolf targeting a werewolf")
+	}
+	if g.Act(2, 3) != ErrNoAction {
+		t.Errorf("expected ErrNoAction for a villager")
+	}
+
+	// The doctor saves the werewolf's target.
+	g.Act(0, 2)
+	g.Act(1, 2)
+	if !g.ActionsDone() {
+		t.Errorf("unexpected value for ActionsDone(), got %t, want %t", false, true)
+	}
+	res, _ := g.EndNight()
+	if !res.Saved || res.Killed != nil {
+		t.Errorf("expected the doctor to save the target")
+	}
+
+	// Lynching the werewolf wins the game for the village.
+	g.Vote(1, 0)
+	g.Vote(2, 0)
+	if count, _ := g.Vote(3, 0); count != 3 {
+		t.Errorf("unexpected vote count, got %d, want %d", count, 3)
+	}
+	if !g.Majority() {
+		t.Errorf("unexpected value for Majority(), got %t, want %t", false, true)
+	}
+	day, _ := g.EndDay()
+	if day.Lynched == nil || day.Lynched.Uid != 0 {
+		t.Errorf("expected player 0 to be lynched")
+	}
+	if g.Winner() != TeamVillage || g.Phase() != PhaseOver {
+		t.Errorf("unexpected winner, got %s, want %s", g.Winner(), TeamVillage)
+	}
+}
+
+func TestWolvesWin(t *testing.T) {
+	g := New(Config{Roles: []Role{{Name: "Werewolf", Team: TeamWolves, Action: ActionKill, Count: 1}}})
+	for uid := 0; uid < 3; uid++ {
+		g.Join(uid, "player")
+	}
+	g.Start(func(n int) int { return n - 1 })
+	g.Act(0, 1)
+	g.EndNight()
+	if g.Winner() != TeamWolves {
+		t.Errorf("unexpected winner, got %s, want %s", g.Winner(), TeamWolves)
+	}
+}
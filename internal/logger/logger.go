@@ -22,8 +22,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/MangosArentLiterature/Athena/internal/webhook"
 )
 
 type LogLevel int
@@ -111,20 +109,30 @@ func LogFatalf(format string, v ...interface{}) {
 	log(Fatal, fmt.Sprintf(format, v...))
 }
 
-// WriteReport flushes a given area buffer to a report file.
-func WriteReport(name string, buffer []string) {
+// WriteReport flushes a given area buffer to a report file, returning the file's name.
+func WriteReport(name string, buffer []string) string {
 	fileLock.Lock()
+	defer fileLock.Unlock()
 	fname := fmt.Sprintf("report-%v-%v.log", time.Now().UTC().Format("2006-01-02T150405Z"), name)
 	fcontents := []byte(strings.Join(buffer, "\n"))
-	err := webhook.PostReport(fname, string(fcontents))
+	err := os.WriteFile(LogPath+"/"+fname, fcontents, 0755)
 	if err != nil {
 		LogError(err.Error())
 	}
-	err = os.WriteFile(LogPath+"/"+fname, fcontents, 0755)
+	return fname
+}
+
+// WriteCase writes a case transcript to a file, returning the file's name.
+func WriteCase(name string, transcript []string) string {
+	fileLock.Lock()
+	defer fileLock.Unlock()
+	fname := fmt.Sprintf("case-%v-%v.log", time.Now().UTC().Format("2006-01-02T150405Z"), name)
+	fcontents := []byte(strings.Join(transcript, "\n"))
+	err := os.WriteFile(LogPath+"/"+fname, fcontents, 0755)
 	if err != nil {
 		LogError(err.Error())
 	}
-	fileLock.Unlock()
+	return fname
 }
 
 // WriteAudit writes a line to the server's audit log.
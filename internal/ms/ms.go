@@ -19,7 +19,11 @@ package ms
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/MangosArentLiterature/Athena/internal/logger"
@@ -31,37 +35,163 @@ type Advertisement struct {
 	Players int    `json:"players"`
 	Name    string `json:"name"`
 	Desc    string `json:"description"`
+	Areas   int    `json:"areas,omitempty"`
+	Casing  int    `json:"casing,omitempty"`
+	Version string `json:"version,omitempty"`
 }
 
-// Advertise begins the server's advertising routine.
-func Advertise(msUrl string, advert Advertisement, updatePlayers chan (int), done chan (struct{})) {
-	postServer(msUrl, advert)
-	ticker := time.NewTicker(5 * time.Minute)
-	for {
+// Status is the result of the last advertisement posted to a master server.
+type Status struct {
+	Addr string
+	Time time.Time
+	Err  error
+}
+
+// Advertiser periodically posts the server's advertisement to one or more master servers.
+type Advertiser struct {
+	mu      sync.Mutex
+	advert  Advertisement
+	status  map[string]Status
+	addrs   []string
+	updates []chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+	client  *http.Client
+}
+
+var (
+	RefreshInterval = 5 * time.Minute  // How often the advertisement is reposted when nothing changes.
+	DebounceDelay   = 5 * time.Second  // How long to wait for further changes before posting an update.
+	MinBackoff      = 10 * time.Second // The initial delay before retrying a failed post.
+	MaxBackoff      = 5 * time.Minute  // The maximum delay before retrying a failed post.
+)
+
+// NewAdvertiser returns a new advertiser for the given master servers.
+func NewAdvertiser(addrs []string, advert Advertisement) *Advertiser {
+	a := &Advertiser{
+		advert: advert,
+		status: make(map[string]Status),
+		addrs:  addrs,
+		done:   make(chan struct{}),
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+	for range addrs {
+		a.updates = append(a.updates, make(chan struct{}, 1))
+	}
+	return a
+}
+
+// Start begins advertising on each master server.
+func (a *Advertiser) Start() {
+	for i, addr := range a.addrs {
+		a.wg.Add(1)
+		go a.advertise(addr, a.updates[i])
+	}
+}
+
+// Stop stops advertising.
+func (a *Advertiser) Stop() {
+	close(a.done)
+	a.wg.Wait()
+}
+
+// Update changes the advertisement, posting it once no further changes have been made for DebounceDelay.
+// Update never blocks.
+func (a *Advertiser) Update(f func(advert *Advertisement)) {
+	a.mu.Lock()
+	f(&a.advert)
+	a.mu.Unlock()
+	for _, u := range a.updates {
 		select {
-		case <-ticker.C:
-			postServer(msUrl, advert)
-		case advert.Players = <-updatePlayers:
-			postServer(msUrl, advert)
-		case <-done:
-			ticker.Stop()
-			return
+		case u <- struct{}{}:
+		default:
 		}
 	}
 }
 
-// postServer sends an advertisement to the master server.
-func postServer(msUrl string, advert Advertisement) {
-	data, err := json.Marshal(advert)
-	if err != nil {
-		logger.LogErrorf("Failed to post advertisement: %v", err)
-		return
+// Status returns the result of the last post to each master server.
+func (a *Advertiser) Status() []Status {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var l []Status
+	for _, addr := range a.addrs {
+		s, ok := a.status[addr]
+		if !ok {
+			s = Status{Addr: addr}
+		}
+		l = append(l, s)
 	}
+	return l
+}
 
-	resp, err := http.Post(msUrl, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		logger.LogErrorf("Failed to post advertisement: %v", err)
-		return
+// advertise runs the advertising routine for a single master server.
+func (a *Advertiser) advertise(addr string, update chan struct{}) {
+	defer a.wg.Done()
+	var (
+		backoff  time.Duration
+		debounce <-chan time.Time
+	)
+	next := time.NewTimer(0)
+	defer next.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-update:
+			// While backing off, the retry posts the latest advertisement anyway.
+			if debounce == nil && backoff == 0 {
+				debounce = time.After(DebounceDelay)
+			}
+			continue
+		case <-debounce:
+			debounce = nil
+			if backoff > 0 {
+				continue
+			}
+		case <-next.C:
+		}
+
+		var wait time.Duration
+		if err := a.post(addr); err != nil {
+			if backoff == 0 {
+				backoff = MinBackoff
+			} else if backoff *= 2; backoff > MaxBackoff {
+				backoff = MaxBackoff
+			}
+			logger.LogErrorf("Failed to post advertisement to %v, retrying in %v: %v", addr, backoff, err)
+			wait = backoff
+		} else {
+			backoff = 0
+			wait = RefreshInterval
+		}
+		if !next.Stop() {
+			select {
+			case <-next.C:
+			default:
+			}
+		}
+		next.Reset(wait)
+	}
+}
+
+// post sends the current advertisement to a master server, recording the result.
+func (a *Advertiser) post(addr string) error {
+	a.mu.Lock()
+	data, err := json.Marshal(a.advert)
+	a.mu.Unlock()
+	if err == nil {
+		var resp *http.Response
+		resp, err = a.client.Post(addr, "application/json", bytes.NewBuffer(data))
+		if err == nil {
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+				err = fmt.Errorf("%v: %v", resp.Status, strings.TrimSpace(string(body)))
+			}
+			resp.Body.Close()
+		}
 	}
-	resp.Body.Close()
+	a.mu.Lock()
+	a.status[addr] = Status{Addr: addr, Time: time.Now().UTC(), Err: err}
+	a.mu.Unlock()
+	return err
 }
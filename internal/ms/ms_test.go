@@ -0,0 +1,81 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package ms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdvertiser(t *testing.T) {
+	DebounceDelay = 50 * time.Millisecond
+	MinBackoff = 10 * time.Millisecond
+
+	posts := make(chan Advertisement, 10)
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// The first post fails, and should be retried.
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var advert Advertisement
+		json.NewDecoder(r.Body).Decode(&advert)
+		posts <- advert
+	}))
+	defer srv.Close()
+
+	a := NewAdvertiser([]string{srv.URL}, Advertisement{Name: "test", Players: 0})
+	a.Start()
+	defer a.Stop()
+
+	select {
+	case advert := <-posts:
+		if advert.Name != "test" {
+			t.Errorf("unexpected advertisement name, got %v, want %v", advert.Name, "test")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("advertisement was not retried after failing")
+	}
+
+	// Several updates in quick succession should result in a single post.
+	for i := 1; i <= 5; i++ {
+		n := i
+		a.Update(func(advert *Advertisement) { advert.Players = n })
+	}
+	select {
+	case advert := <-posts:
+		if advert.Players != 5 {
+			t.Errorf("unexpected player count, got %d, want %d", advert.Players, 5)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("advertisement was not posted after update")
+	}
+	select {
+	case <-posts:
+		t.Error("updates were not debounced")
+	case <-time.After(3 * DebounceDelay):
+	}
+
+	if st := a.Status(); len(st) != 1 || st[0].Err != nil {
+		t.Errorf("unexpected status after successful post: %v", st)
+	}
+}
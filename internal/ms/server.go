@@ -0,0 +1,181 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package ms
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerEntry is a server listed by the master server.
+type ServerEntry struct {
+	IP string `json:"ip"`
+	Advertisement
+}
+
+type listing struct {
+	entry    ServerEntry
+	lastSeen time.Time
+}
+
+// Server is a master server, which accepts advertisements from AO servers and serves the resulting server list.
+type Server struct {
+	mu      sync.Mutex
+	servers map[string]*listing
+	secret  string
+	expiry  time.Duration
+}
+
+const (
+	maxNameLength = 100
+	maxDescLength = 1000
+	maxPerIP      = 10 // The most servers a single IP can have listed at once.
+)
+
+// NewServer returns a new master server.
+// Servers that stop advertising are removed after the given expiry.
+// If secret is not empty, requests must provide it, either as a "secret" query parameter or a bearer token.
+func NewServer(secret string, expiry time.Duration) *Server {
+	s := &Server{
+		servers: make(map[string]*listing),
+		secret:  secret,
+		expiry:  expiry,
+	}
+	go s.sweep()
+	return s
+}
+
+// sweep periodically removes expired servers, so servers that are never listed do not accumulate.
+func (s *Server) sweep() {
+	for range time.Tick(s.expiry) {
+		s.mu.Lock()
+		s.prune()
+		s.mu.Unlock()
+	}
+}
+
+// prune removes expired servers. The caller must hold the server's lock.
+func (s *Server) prune() {
+	for key, srv := range s.servers {
+		if time.Since(srv.lastSeen) > s.expiry {
+			delete(s.servers, key)
+		}
+	}
+}
+
+// ServeHTTP handles a request to the master server.
+// POST requests advertise a server, and GET requests return the server list.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "invalid secret", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Servers())
+	case http.MethodPost:
+		var advert Advertisement
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&advert)
+		if err != nil {
+			http.Error(w, "invalid advertisement", http.StatusBadRequest)
+			return
+		}
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		if err := s.Advertise(ip, advert); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Advertise adds or refreshes a server in the server list.
+func (s *Server) Advertise(ip string, advert Advertisement) error {
+	switch {
+	case advert.Port <= 0 || advert.Port > 65535:
+		return fmt.Errorf("invalid port")
+	case advert.WSPort < 0 || advert.WSPort > 65535:
+		return fmt.Errorf("invalid ws_port")
+	case strings.TrimSpace(advert.Name) == "" || len(advert.Name) > maxNameLength:
+		return fmt.Errorf("invalid name")
+	case len(advert.Desc) > maxDescLength:
+		return fmt.Errorf("invalid description")
+	case advert.Players < 0:
+		return fmt.Errorf("invalid players")
+	}
+	key := net.JoinHostPort(ip, strconv.Itoa(advert.Port))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.servers[key]; !ok {
+		s.prune()
+		var count int
+		for _, srv := range s.servers {
+			if srv.entry.IP == ip {
+				count++
+			}
+		}
+		if count >= maxPerIP {
+			return fmt.Errorf("too many servers from this address")
+		}
+	}
+	s.servers[key] = &listing{entry: ServerEntry{IP: ip, Advertisement: advert}, lastSeen: time.Now()}
+	return nil
+}
+
+// Servers returns the current server list, removing any expired servers.
+func (s *Server) Servers() []ServerEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	l := []ServerEntry{}
+	for _, srv := range s.servers {
+		l = append(l, srv.entry)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].Players != l[j].Players {
+			return l[i].Players > l[j].Players
+		}
+		return l[i].Name < l[j].Name
+	})
+	return l
+}
+
+// authorized returns whether a request provides the master server's secret.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.secret == "" {
+		return true
+	}
+	provided := r.URL.Query().Get("secret")
+	if provided == "" {
+		provided = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(s.secret)) == 1
+}
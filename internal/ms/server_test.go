@@ -0,0 +1,88 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package ms
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	srv := httptest.NewServer(NewServer("hunter2", 50*time.Millisecond))
+	defer srv.Close()
+
+	post := func(url string, advert Advertisement) int {
+		data, _ := json.Marshal(advert)
+		resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	list := func() []ServerEntry {
+		resp, err := http.Get(srv.URL + "?secret=hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var l []ServerEntry
+		json.NewDecoder(resp.Body).Decode(&l)
+		return l
+	}
+
+	// Requests without the secret are rejected.
+	if code := post(srv.URL, Advertisement{Port: 27016, Name: "foo"}); code != http.StatusUnauthorized {
+		t.Errorf("unexpected status for missing secret, got %d, want %d", code, http.StatusUnauthorized)
+	}
+	// Invalid advertisements are rejected.
+	if code := post(srv.URL+"?secret=hunter2", Advertisement{Port: 0, Name: "foo"}); code != http.StatusBadRequest {
+		t.Errorf("unexpected status for invalid port, got %d, want %d", code, http.StatusBadRequest)
+	}
+
+	if code := post(srv.URL+"?secret=hunter2", Advertisement{Port: 27016, Name: "foo", Players: 3}); code != http.StatusOK {
+		t.Fatalf("unexpected status for valid advertisement, got %d, want %d", code, http.StatusOK)
+	}
+	l := list()
+	if len(l) != 1 || l[0].Name != "foo" || l[0].Players != 3 || l[0].IP == "" {
+		t.Fatalf("unexpected server list: %+v", l)
+	}
+
+	// Readvertising updates the existing entry.
+	post(srv.URL+"?secret=hunter2", Advertisement{Port: 27016, Name: "foo", Players: 4})
+	if l = list(); len(l) != 1 || l[0].Players != 4 {
+		t.Errorf("unexpected server list after update: %+v", l)
+	}
+
+	// The entry expires once the server stops advertising.
+	time.Sleep(100 * time.Millisecond)
+	if l = list(); len(l) != 0 {
+		t.Errorf("unexpected server list after expiry: %+v", l)
+	}
+
+	// A single address can only list so many servers.
+	for port := 1; port <= maxPerIP; port++ {
+		post(srv.URL+"?secret=hunter2", Advertisement{Port: port, Name: "foo"})
+	}
+	if code := post(srv.URL+"?secret=hunter2", Advertisement{Port: maxPerIP + 1, Name: "foo"}); code != http.StatusBadRequest {
+		t.Errorf("unexpected status over the per-address limit, got %d, want %d", code, http.StatusBadRequest)
+	}
+}
@@ -17,12 +17,18 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>. */
 package permissions
 
 import (
+	"fmt"
 	"math"
+	"sort"
+	"strings"
 )
 
 type Role struct {
 	Name        string   `toml:"name"`
 	Permissions []string `toml:"permissions"`
+	Inherit     []string `toml:"inherit"`
+	Deny        []string `toml:"deny"`
+	perms       uint64
 }
 
 var PermissionField = map[string]uint64{
@@ -42,15 +48,102 @@ var PermissionField = map[string]uint64{
 	"ADMIN":       math.MaxUint64,
 }
 
+// AreaScoped is the set of permissions that may be granted within a single area.
+// Anything outside of it, including ADMIN, only applies when held server-wide.
+var AreaScoped = PermissionField["CM"] | PermissionField["KICK"] | PermissionField["BYPASS_LOCK"] | PermissionField["MOD_EVI"] |
+	PermissionField["MODIFY_AREA"] | PermissionField["MOVE_USERS"] | PermissionField["MOD_SPEAK"] | PermissionField["MUTE"] | PermissionField["LOG"]
+
 // GetPermissions returns the permissions for a role.
+// Inherited and denied permissions are only accounted for once the role has been resolved with ResolveRoles.
 func (r *Role) GetPermissions() uint64 {
-	var last uint64
-	var current uint64
-	for _, perm := range r.Permissions {
-		current = PermissionField[perm] | last
-		last = current
+	return r.perms
+}
+
+// ParsePermissions returns the combined value of a list of permission names, or an error if a name is not recognized.
+func ParsePermissions(names []string) (uint64, error) {
+	var p uint64
+	for _, name := range names {
+		v, ok := PermissionField[strings.ToUpper(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown permission %q", name)
+		}
+		p |= v
+	}
+	return p, nil
+}
+
+// PermissionNames returns the names of the permissions contained in a permission value.
+func PermissionNames(perm uint64) []string {
+	if perm == PermissionField["ADMIN"] {
+		return []string{"ADMIN"}
+	}
+	var names []string
+	for name, v := range PermissionField {
+		if v != 0 && name != "ADMIN" && HasPermission(perm, v) {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return PermissionField[names[i]] < PermissionField[names[j]] })
+	return names
+}
+
+// ResolveRoles validates a list of roles and computes each role's permissions,
+// applying inherited roles first and removing denied permissions last.
+func ResolveRoles(roles []Role) error {
+	index := make(map[string]int, len(roles))
+	for i, r := range roles {
+		if strings.TrimSpace(r.Name) == "" {
+			return fmt.Errorf("role %v has no name", i)
+		}
+		if _, ok := index[r.Name]; ok {
+			return fmt.Errorf("role %q is defined more than once", r.Name)
+		}
+		index[r.Name] = i
+	}
+
+	resolved := make(map[string]bool, len(roles))
+	visiting := make(map[string]bool)
+	var resolve func(i int) error
+	resolve = func(i int) error {
+		r := &roles[i]
+		if resolved[r.Name] {
+			return nil
+		}
+		if visiting[r.Name] {
+			return fmt.Errorf("role %q inherits from itself", r.Name)
+		}
+		visiting[r.Name] = true
+		defer delete(visiting, r.Name)
+
+		var p uint64
+		for _, parent := range r.Inherit {
+			j, ok := index[parent]
+			if !ok {
+				return fmt.Errorf("role %q inherits from unknown role %q", r.Name, parent)
+			}
+			if err := resolve(j); err != nil {
+				return err
+			}
+			p |= roles[j].perms
+		}
+		own, err := ParsePermissions(r.Permissions)
+		if err != nil {
+			return fmt.Errorf("role %q: %v", r.Name, err)
+		}
+		deny, err := ParsePermissions(r.Deny)
+		if err != nil {
+			return fmt.Errorf("role %q: %v", r.Name, err)
+		}
+		r.perms = (p | own) &^ deny
+		resolved[r.Name] = true
+		return nil
+	}
+	for i := range roles {
+		if err := resolve(i); err != nil {
+			return err
+		}
 	}
-	return current
+	return nil
 }
 
 // HasPermission checks if the supplied permissions matches the required permissions.
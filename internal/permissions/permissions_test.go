@@ -0,0 +1,74 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package permissions
+
+import "testing"
+
+func TestResolveRoles(t *testing.T) {
+	roles := []Role{
+		{Name: "moderator", Inherit: []string{"helper"}, Permissions: []string{"KICK", "BAN"}},
+		{Name: "helper", Permissions: []string{"CM", "MUTE"}},
+		{Name: "trial", Inherit: []string{"moderator"}, Deny: []string{"BAN"}},
+		{Name: "admin", Permissions: []string{"ADMIN"}, Deny: []string{"LOG"}},
+	}
+	if err := ResolveRoles(roles); err != nil {
+		t.Fatalf("resolving valid roles: %v", err)
+	}
+
+	// Inherited permissions are combined with the role's own.
+	want := PermissionField["CM"] | PermissionField["MUTE"] | PermissionField["KICK"] | PermissionField["BAN"]
+	if got := roles[0].GetPermissions(); got != want {
+		t.Errorf("unexpected permissions for moderator, got %d, want %d", got, want)
+	}
+
+	// Denied permissions are removed after inheritance.
+	if HasPermission(roles[2].GetPermissions(), PermissionField["BAN"]) {
+		t.Errorf("trial role has denied permission BAN")
+	}
+	if !HasPermission(roles[2].GetPermissions(), PermissionField["KICK"]) {
+		t.Errorf("trial role is missing inherited permission KICK")
+	}
+	if HasPermission(roles[3].GetPermissions(), PermissionField["LOG"]) {
+		t.Errorf("admin role has denied permission LOG")
+	}
+}
+
+func TestResolveRolesInvalid(t *testing.T) {
+	tests := map[string][]Role{
+		"unknown permission": {{Name: "a", Permissions: []string{"KICKK"}}},
+		"unknown deny":       {{Name: "a", Deny: []string{"FOO"}}},
+		"unknown parent":     {{Name: "a", Inherit: []string{"b"}}},
+		"cycle":              {{Name: "a", Inherit: []string{"b"}}, {Name: "b", Inherit: []string{"a"}}},
+		"duplicate":          {{Name: "a"}, {Name: "a"}},
+	}
+	for name, roles := range tests {
+		if err := ResolveRoles(roles); err == nil {
+			t.Errorf("%v: expected error, got nil", name)
+		}
+	}
+}
+
+func TestPermissionNames(t *testing.T) {
+	names := PermissionNames(PermissionField["MUTE"] | PermissionField["CM"])
+	if len(names) != 2 || names[0] != "CM" || names[1] != "MUTE" {
+		t.Errorf("unexpected permission names, got %v, want %v", names, []string{"CM", "MUTE"})
+	}
+	names = PermissionNames(PermissionField["ADMIN"])
+	if len(names) != 1 || names[0] != "ADMIN" {
+		t.Errorf("unexpected permission names, got %v, want %v", names, []string{"ADMIN"})
+	}
+}
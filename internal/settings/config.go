@@ -21,11 +21,16 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/MangosArentLiterature/Athena/internal/area"
+	"github.com/MangosArentLiterature/Athena/internal/dice"
+	"github.com/MangosArentLiterature/Athena/internal/game"
 	"github.com/MangosArentLiterature/Athena/internal/permissions"
+	"github.com/MangosArentLiterature/Athena/internal/webhook"
 )
 
 // Stores the path to the config directory
@@ -34,6 +39,8 @@ var ConfigPath string
 type Config struct {
 	ServerConfig `toml:"Server"`
 	MSConfig     `toml:"MasterServer"`
+	MSHostConfig `toml:"MasterServerHost"`
+	Webhooks     []webhook.Webhook `toml:"Webhook"`
 }
 
 type ServerConfig struct {
@@ -56,10 +63,22 @@ type ServerConfig struct {
 	MaxSide      int    `toml:"max_sides"`
 	Motd         string `toml:"motd"`
 	MaxStatement int    `toml:"max_testimony"`
+	MaxTempAreas int    `toml:"max_temp_areas"`
+	ICReplay     int    `toml:"ic_replay_length"`
+	CaseExpiry   string `toml:"case_listing_expiry"`
+	CaseCooldown string `toml:"case_announce_cooldown"`
+	JuryTimeout  string `toml:"jury_timeout"`
 }
 type MSConfig struct {
-	Advertise bool   `toml:"advertise"`
-	MSAddr    string `toml:"addr"`
+	Advertise    bool     `toml:"advertise"`
+	MSAddr       string   `toml:"addr"`
+	ExtraMSAddrs []string `toml:"extra_addrs"`
+}
+type MSHostConfig struct {
+	EnableMSHost bool   `toml:"enable"`
+	MSHostPort   int    `toml:"port"`
+	MSHostSecret string `toml:"secret"`
+	MSHostExpiry string `toml:"expiry"`
 }
 
 // Returns a default configuration.
@@ -82,11 +101,20 @@ func defaultConfig() *Config {
 			MaxDice:      100,
 			MaxSide:      100,
 			MaxStatement: 10,
+			CaseExpiry:   "1h",
+			CaseCooldown: "5m",
+			JuryTimeout:  "2m",
 		},
 		MSConfig{
 			Advertise: false,
 			MSAddr:    "https://servers.aceattorneyonline.com/servers",
 		},
+		MSHostConfig{
+			EnableMSHost: false,
+			MSHostPort:   27018,
+			MSHostExpiry: "10m",
+		},
+		nil,
 	}
 }
 
@@ -111,25 +139,34 @@ func GetConfig() (*Config, error) {
 	return conf, nil
 }
 
-// LoadMusic reads the server's music file, returning it's contents.
-func LoadMusic() ([]string, error) {
+// LoadMusic reads a music file, returning it's contents and the lengths of songs which declare one.
+// A song's length is declared in seconds after it's name, such as "song.opus:183".
+func LoadMusic(file string) ([]string, map[string]time.Duration, error) {
 	var musicList []string
-	f, err := os.Open(ConfigPath + "/music.txt")
+	lengths := make(map[string]time.Duration)
+	f, err := os.Open(ConfigPath + file)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer f.Close()
 	in := bufio.NewScanner(f)
 	for in.Scan() {
-		musicList = append(musicList, in.Text())
+		song := in.Text()
+		if i := strings.LastIndex(song, ":"); i != -1 {
+			if secs, err := strconv.Atoi(song[i+1:]); err == nil && secs > 0 {
+				song = song[:i]
+				lengths[song] = time.Duration(secs) * time.Second
+			}
+		}
+		musicList = append(musicList, song)
 	}
 	if len(musicList) == 0 {
-		return nil, fmt.Errorf("empty musiclist")
+		return nil, nil, fmt.Errorf("empty musiclist")
 	}
 	if strings.ContainsRune(musicList[0], '.') {
 		musicList = append([]string{"Songs"}, musicList...)
 	}
-	return musicList, nil
+	return musicList, lengths, nil
 }
 
 // LoadFile reads a server file, returning it's contents.
@@ -147,32 +184,86 @@ func LoadFile(file string) ([]string, error) {
 	return l, nil
 }
 
-// LoadAreas reads the server's area configuration file, returning it's contents.
-func LoadAreas() ([]area.AreaData, error) {
-	var conf struct {
-		Area []area.AreaData
-	}
-	_, err := toml.DecodeFile(ConfigPath+"/areas.toml", &conf)
+type AreaConfig struct {
+	Hubs     []area.HubData  `toml:"Hub"`
+	Areas    []area.AreaData `toml:"Area"`
+	Template area.AreaData   `toml:"Template"`
+}
+
+// LoadAreas reads the server's area configuration file.
+func LoadAreas() (*AreaConfig, error) {
+	conf := &AreaConfig{}
+	_, err := toml.DecodeFile(ConfigPath+"/areas.toml", conf)
 	if err != nil {
-		return conf.Area, err
+		return nil, err
 	}
-	if len(conf.Area) == 0 {
-		return conf.Area, fmt.Errorf("empty arealist")
+	if len(conf.Areas) == 0 {
+		return nil, fmt.Errorf("empty arealist")
 	}
-	return conf.Area, nil
+	return conf, nil
 }
 
-// LoadAreas reads the server's role configuration file, returning it's contents.
-func LoadRoles() ([]permissions.Role, error) {
+// LoadRoles reads the server's role configuration file, returning it's roles and command permission overrides.
+func LoadRoles() ([]permissions.Role, map[string]string, error) {
 	var conf struct {
-		Role []permissions.Role
+		Role     []permissions.Role
+		Commands map[string]string
 	}
 	_, err := toml.DecodeFile(ConfigPath+"/roles.toml", &conf)
 	if err != nil {
-		return conf.Role, err
+		return conf.Role, conf.Commands, err
 	}
 	if len(conf.Role) == 0 {
-		return conf.Role, fmt.Errorf("empty rolelist")
+		return conf.Role, conf.Commands, fmt.Errorf("empty rolelist")
+	}
+	err = permissions.ResolveRoles(conf.Role)
+	if err != nil {
+		return conf.Role, conf.Commands, err
+	}
+	return conf.Role, conf.Commands, nil
+}
+
+// LoadGames reads the server's game configuration file. If the file does not exist, the default werewolf roles are used.
+func LoadGames() (game.Config, error) {
+	conf := game.Config{
+		DayLength:   "5m",
+		NightLength: "90s",
+		MinPlayers:  5,
+	}
+	_, err := toml.DecodeFile(ConfigPath+"/games.toml", &conf)
+	if err != nil && !os.IsNotExist(err) {
+		return conf, err
+	}
+	if len(conf.Roles) == 0 {
+		conf.Roles = []game.Role{
+			{Name: "Werewolf", Team: game.TeamWolves, Action: game.ActionKill, Count: 1, Description: "Each night, choose a player to kill."},
+			{Name: "Seer", Team: game.TeamVillage, Action: game.ActionInvestigate, Count: 1, Description: "Each night, learn which team a player is on."},
+			{Name: "Doctor", Team: game.TeamVillage, Action: game.ActionProtect, Count: 1, Description: "Each night, choose a player to protect from the werewolves."},
+		}
+	}
+	return conf, conf.Validate()
+}
+
+// LoadTables reads the server's random table file. If the file does not exist, no tables are loaded.
+func LoadTables() ([]dice.Table, error) {
+	var conf struct {
+		Table []dice.Table
+	}
+	_, err := toml.DecodeFile(ConfigPath+"/tables.toml", &conf)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, t := range conf.Table {
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		if names[strings.ToLower(t.Name)] {
+			return nil, fmt.Errorf("duplicate table %v", t.Name)
+		}
+		names[strings.ToLower(t.Name)] = true
 	}
-	return conf.Role, nil
+	return conf.Table, nil
 }
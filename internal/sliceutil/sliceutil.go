@@ -27,6 +27,19 @@ func ContainsString(container []string, value string) bool {
 	return false
 }
 
+// EqualStrings checks if two string slices contain the same values in the same order.
+func EqualStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // ContainsString checks if an int is within an int slice.
 func ContainsInt(container []int, value int) bool {
 	for _, x := range container {
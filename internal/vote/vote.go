@@ -0,0 +1,190 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package vote
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClosed        = errors.New("vote is closed")
+	ErrInvalidOption = errors.New("invalid option")
+)
+
+// Result is the tally for one of a vote's options.
+type Result struct {
+	Option string
+	Count  int
+	Voters []string
+}
+
+// ballot is a single voter's choice.
+type ballot struct {
+	name   string
+	option int
+}
+
+// Vote is a vote between a set of options. Voters are identified by a key chosen by the caller, such as a UID or IPID.
+type Vote struct {
+	mu        sync.Mutex
+	question  string
+	options   []string
+	anonymous bool
+	open      bool
+	ballots   map[string]ballot
+	order     []string
+	timer     *time.Timer
+}
+
+// New returns a new open vote.
+func New(question string, options []string, anonymous bool) *Vote {
+	return &Vote{
+		question:  question,
+		options:   options,
+		anonymous: anonymous,
+		open:      true,
+		ballots:   make(map[string]ballot),
+	}
+}
+
+// Question returns the vote's question.
+func (v *Vote) Question() string {
+	return v.question
+}
+
+// Options returns the vote's options.
+func (v *Vote) Options() []string {
+	return v.options
+}
+
+// Anonymous returns whether the vote hides who voted for each option.
+func (v *Vote) Anonymous() bool {
+	return v.anonymous
+}
+
+// Open returns whether the vote is accepting votes.
+func (v *Vote) Open() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.open
+}
+
+// Option returns the index of an option, given either it's number or it's name. It returns -1 if there is no such option.
+func (v *Vote) Option(s string) int {
+	for i, o := range v.options {
+		if strings.EqualFold(s, o) {
+			return i
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > len(v.options) {
+		return -1
+	}
+	return n - 1
+}
+
+// Cast casts a voter's vote, replacing any previous vote they cast. It returns true if the voter had already voted.
+func (v *Vote) Cast(voter string, name string, option int) (bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.open {
+		return false, ErrClosed
+	}
+	if option < 0 || option >= len(v.options) {
+		return false, ErrInvalidOption
+	}
+	_, changed := v.ballots[voter]
+	if !changed {
+		v.order = append(v.order, voter)
+	}
+	v.ballots[voter] = ballot{name: name, option: option}
+	return changed, nil
+}
+
+// Count returns the number of votes cast.
+func (v *Vote) Count() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.ballots)
+}
+
+// Results returns the tally for each option. Voter names are omitted from anonymous votes.
+func (v *Vote) Results() []Result {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	results := make([]Result, len(v.options))
+	for i, o := range v.options {
+		results[i].Option = o
+	}
+	for _, voter := range v.order {
+		b := v.ballots[voter]
+		results[b.option].Count++
+		if !v.anonymous {
+			results[b.option].Voters = append(results[b.option].Voters, b.name)
+		}
+	}
+	return results
+}
+
+// Winners returns the options with the most votes. It returns nothing if no votes were cast.
+func (v *Vote) Winners() []string {
+	var winners []string
+	max := 0
+	for _, r := range v.Results() {
+		switch {
+		case r.Count == 0:
+		case r.Count > max:
+			max = r.Count
+			winners = []string{r.Option}
+		case r.Count == max:
+			winners = append(winners, r.Option)
+		}
+	}
+	return winners
+}
+
+// CloseAfter closes the vote after the given duration, calling onClose if it was still open.
+func (v *Vote) CloseAfter(d time.Duration, onClose func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.timer != nil {
+		v.timer.Stop()
+	}
+	v.timer = time.AfterFunc(d, func() {
+		if v.Close() {
+			onClose()
+		}
+	})
+}
+
+// Close closes the vote. It returns false if the vote was already closed.
+func (v *Vote) Close() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.open {
+		return false
+	}
+	v.open = false
+	if v.timer != nil {
+		v.timer.Stop()
+	}
+	return true
+}
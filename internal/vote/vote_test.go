@@ -0,0 +1,64 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package vote
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVote(t *testing.T) {
+	v := New("Verdict?", []string{"Guilty", "Not guilty"}, false)
+
+	if v.Option("not GUILTY") != 1 || v.Option("1") != 0 || v.Option("3") != -1 {
+		t.Errorf("unexpected value for Option()")
+	}
+	if _, err := v.Cast("1", "Phoenix", 2); err != ErrInvalidOption {
+		t.Errorf("unexpected error for invalid option, got %v, want %v", err, ErrInvalidOption)
+	}
+	v.Cast("1", "Phoenix", 0)
+	v.Cast("2", "Maya", 0)
+	changed, _ := v.Cast("1", "Phoenix", 1)
+	if !changed {
+		t.Errorf("unexpected value for changed vote, got %t, want %t", changed, true)
+	}
+	if v.Count() != 2 {
+		t.Errorf("unexpected value for Count(), got %d, want %d", v.Count(), 2)
+	}
+	r := v.Results()
+	if r[0].Count != 1 || r[1].Count != 1 || r[1].Voters[0] != "Phoenix" {
+		t.Errorf("unexpected results, got %v", r)
+	}
+	if w := v.Winners(); len(w) != 2 {
+		t.Errorf("unexpected number of winners, got %d, want %d", len(w), 2)
+	}
+
+	// Close after a timeout.
+	closed := make(chan struct{})
+	v.CloseAfter(10*time.Millisecond, func() { close(closed) })
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("vote did not close after timeout")
+	}
+	if _, err := v.Cast("3", "Edgeworth", 0); err != ErrClosed {
+		t.Errorf("unexpected error for closed vote, got %v, want %v", err, ErrClosed)
+	}
+	if v.Close() {
+		t.Errorf("unexpected value for Close() on closed vote, got %t, want %t", true, false)
+	}
+}
@@ -14,45 +14,294 @@ GNU Affero General Public License for more details.
 You should have received a copy of the GNU Affero General Public License
 along with this program.  If not, see <https://www.gnu.org/licenses/>. */
 
+// Package webhook posts server events to Discord and generic JSON webhooks.
 package webhook
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/MangosArentLiterature/Athena/internal/logger"
 	"github.com/ecnepsnai/discord"
 )
 
+type EventType string
+
+const (
+	EventModcall EventType = "modcall"
+	EventReport  EventType = "report"
+	EventBan     EventType = "ban"
+	EventKick    EventType = "kick"
+	EventMute    EventType = "mute"
+	EventLogin   EventType = "login"
+	EventStart   EventType = "start"
+	EventStop    EventType = "stop"
+	EventRaid    EventType = "raid"
+)
+
+var eventTypes = []EventType{EventModcall, EventReport, EventBan, EventKick, EventMute, EventLogin, EventStart, EventStop, EventRaid}
+
+// Webhook is a webhook target, as defined in the server's config.
+type Webhook struct {
+	URL    string   `toml:"url"`
+	Format string   `toml:"format"`
+	Events []string `toml:"events"`
+}
+
+// Field is a named value attached to an event.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Event is a server event to be posted to subscribed webhooks.
+type Event struct {
+	Type        EventType `json:"type"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Fields      []Field   `json:"fields,omitempty"`
+	FileName    string    `json:"file_name,omitempty"`
+	File        string    `json:"file,omitempty"`
+	Time        int64     `json:"time"`
+}
+
+type target struct {
+	url     string
+	discord bool
+	events  map[EventType]bool
+	queue   chan Event
+}
+
+const (
+	queueSize   = 64
+	maxAttempts = 4
+)
+
 var (
 	ServerName  string
 	ServerColor uint32 = 0x05b2f7
+	targets     []*target
+	targetsMu   sync.RWMutex
+	wg          sync.WaitGroup
+	httpClient  = &http.Client{Timeout: 10 * time.Second}
 )
 
-// PostModcall sends a modcall to the discord webhook.
-func PostModcall(character string, area string, reason string) error {
-	e := discord.Embed{
+// Start validates the given webhooks and starts a delivery queue for each of them.
+func Start(hooks []Webhook) error {
+	var l []*target
+	for i, h := range hooks {
+		if strings.TrimSpace(h.URL) == "" {
+			return fmt.Errorf("webhook %v has no url", i)
+		}
+		t := &target{url: h.URL, events: make(map[EventType]bool), queue: make(chan Event, queueSize)}
+		switch strings.ToLower(h.Format) {
+		case "", "discord":
+			t.discord = true
+		case "json":
+			t.discord = false
+		default:
+			return fmt.Errorf("webhook %v has an invalid format %q", i, h.Format)
+		}
+		if len(h.Events) == 0 {
+			return fmt.Errorf("webhook %v is not subscribed to any events", i)
+		}
+		for _, e := range h.Events {
+			if strings.ToLower(e) == "all" {
+				for _, et := range eventTypes {
+					t.events[et] = true
+				}
+				continue
+			}
+			et := EventType(strings.ToLower(e))
+			if !validEvent(et) {
+				return fmt.Errorf("webhook %v has an unknown event %q", i, e)
+			}
+			t.events[et] = true
+		}
+		l = append(l, t)
+	}
+	targetsMu.Lock()
+	targets = append(targets, l...)
+	targetsMu.Unlock()
+	for _, t := range l {
+		wg.Add(1)
+		go t.run()
+	}
+	return nil
+}
+
+// Stop waits for queued events to be delivered, giving up after the given timeout.
+func Stop(timeout time.Duration) {
+	targetsMu.Lock()
+	for _, t := range targets {
+		close(t.queue)
+	}
+	targets = nil
+	targetsMu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logger.LogWarning("Timed out waiting for webhooks to finish sending.")
+	}
+}
+
+// Post queues an event for delivery to all webhooks subscribed to it.
+// Post never blocks; if a webhook's queue is full, the event is dropped for that webhook.
+func Post(e Event) {
+	if e.Time == 0 {
+		e.Time = time.Now().UTC().Unix()
+	}
+	targetsMu.RLock()
+	defer targetsMu.RUnlock()
+	for _, t := range targets {
+		if !t.events[e.Type] {
+			continue
+		}
+		select {
+		case t.queue <- e:
+		default:
+			logger.LogWarningf("Webhook queue is full, dropping %v event.", e.Type)
+		}
+	}
+}
+
+// PostModcall sends a modcall to subscribed webhooks.
+func PostModcall(character string, area string, reason string) {
+	Post(Event{
+		Type:        EventModcall,
 		Title:       fmt.Sprintf("%v sent a modcall in %v.", character, area),
 		Description: reason,
-		Color:       ServerColor,
+	})
+}
+
+// PostReport sends a report file to subscribed webhooks.
+func PostReport(name string, contents string) {
+	Post(Event{
+		Type:     EventReport,
+		Title:    fmt.Sprintf("Report %v", name),
+		FileName: name,
+		File:     contents,
+	})
+}
+
+// validEvent returns whether the given event type exists.
+func validEvent(e EventType) bool {
+	for _, et := range eventTypes {
+		if et == e {
+			return true
+		}
 	}
-	p := discord.PostOptions{
-		Username: ServerName,
-		Embeds:   []discord.Embed{e},
+	return false
+}
+
+// permanentError is a delivery error that retrying will not fix, such as a deleted webhook.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// run delivers queued events to the target until its queue is closed.
+func (t *target) run() {
+	defer wg.Done()
+	for e := range t.queue {
+		backoff := time.Second
+		for attempt := 1; ; attempt++ {
+			err := t.send(e)
+			if err == nil {
+				break
+			}
+			if errors.As(err, &permanentError{}) {
+				logger.LogErrorf("Failed to post %v event to webhook: %v", e.Type, err)
+				break
+			} else if attempt == maxAttempts {
+				logger.LogErrorf("Failed to post %v event to webhook after %v attempts: %v", e.Type, attempt, err)
+				break
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
 	}
-	err := discord.Post(p)
-	return err
 }
 
-// PostReport sends a report file to the discord webhook.
-func PostReport(name string, contents string) error {
-	c := strings.NewReader(contents)
-	f := discord.FileOptions{
-		FileName: name,
-		Reader:   c,
+// send posts a single event to the target.
+func (t *target) send(e Event) error {
+	var (
+		body        bytes.Buffer
+		contentType = "application/json"
+	)
+	if t.discord {
+		p := discord.PostOptions{Username: ServerName}
+		if e.File != "" {
+			p.Content = e.Title
+			w := multipart.NewWriter(&body)
+			fw, err := w.CreateFormFile("file", e.FileName)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(fw, e.File); err != nil {
+				return err
+			}
+			pw, err := w.CreateFormField("payload_json")
+			if err != nil {
+				return err
+			}
+			if err := json.NewEncoder(pw).Encode(p); err != nil {
+				return err
+			}
+			w.Close()
+			contentType = w.FormDataContentType()
+		} else {
+			embed := discord.Embed{
+				Title:       e.Title,
+				Description: e.Description,
+				Color:       ServerColor,
+			}
+			for _, f := range e.Fields {
+				embed.Fields = append(embed.Fields, discord.Field{Name: f.Name, Value: f.Value, Inline: true})
+			}
+			p.Embeds = []discord.Embed{embed}
+			if err := json.NewEncoder(&body).Encode(p); err != nil {
+				return err
+			}
+		}
+	} else {
+		payload := struct {
+			Server string `json:"server"`
+			Event
+		}{ServerName, e}
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return err
+		}
+	}
+
+	resp, err := httpClient.Post(t.url, contentType, &body)
+	if err != nil {
+		return err
 	}
-	p := discord.PostOptions{
-		Username: ServerName,
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("HTTP error %v", resp.StatusCode)
+	default:
+		// Other responses, such as 404 for a deleted webhook, will fail the same way every time.
+		return permanentError{fmt.Errorf("HTTP error %v", resp.StatusCode)}
 	}
-	err := discord.UploadFile(p, f)
-	return err
 }
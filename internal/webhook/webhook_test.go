@@ -0,0 +1,95 @@
+/* Athena - A server for Attorney Online 2 written in Go
+Copyright (C) 2022 MangosArentLiterature <mango@transmenace.dev>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>. */
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPost(t *testing.T) {
+	received := make(chan Event, 4)
+	var fails int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt fails, and should be retried.
+		if fails == 0 {
+			fails++
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var e Event
+		json.NewDecoder(r.Body).Decode(&e)
+		received <- e
+	}))
+	defer srv.Close()
+
+	err := Start([]Webhook{{URL: srv.URL, Format: "json", Events: []string{"ban"}}})
+	if err != nil {
+		t.Fatalf("starting webhooks: %v", err)
+	}
+
+	// Unsubscribed events are not sent.
+	Post(Event{Type: EventKick, Title: "kick"})
+	Post(Event{Type: EventBan, Title: "ban"})
+	Stop(5 * time.Second)
+
+	if len(received) != 1 {
+		t.Fatalf("unexpected number of events received, got %d, want %d", len(received), 1)
+	}
+	if e := <-received; e.Type != EventBan || e.Title != "ban" {
+		t.Errorf("unexpected event received, got %v, want %v", e.Type, EventBan)
+	}
+}
+
+func TestPostPermanentError(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	err := Start([]Webhook{{URL: srv.URL, Format: "json", Events: []string{"ban"}}})
+	if err != nil {
+		t.Fatalf("starting webhooks: %v", err)
+	}
+	Post(Event{Type: EventBan, Title: "ban"})
+	Stop(5 * time.Second)
+
+	// Client errors other than 429 are not retried.
+	if attempts != 1 {
+		t.Errorf("unexpected number of attempts, got %d, want %d", attempts, 1)
+	}
+}
+
+func TestStartInvalid(t *testing.T) {
+	tests := map[string]Webhook{
+		"no url":        {Format: "json", Events: []string{"ban"}},
+		"bad format":    {URL: "http://localhost", Format: "xml", Events: []string{"ban"}},
+		"no events":     {URL: "http://localhost"},
+		"unknown event": {URL: "http://localhost", Events: []string{"explode"}},
+	}
+	for name, h := range tests {
+		if err := Start([]Webhook{h}); err == nil {
+			t.Errorf("%v: expected error, got nil", name)
+			Stop(time.Second)
+		}
+	}
+}